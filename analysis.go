@@ -0,0 +1,428 @@
+package nanovna
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// gridToleranceHz is the maximum per-point frequency difference for two
+// sweeps to be considered on the same grid.
+const gridToleranceHz = 1.0
+
+// minReferenceMag is the smallest reference magnitude used as a divisor;
+// smaller references are clamped to it to avoid blowing up the result.
+const minReferenceMag = 1e-6
+
+// sameGrid reports whether two frequency grids match point for point within tol Hz.
+func sameGrid(a, b []float64, tol float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+// minOpenRotation is the smallest phase excursion across a sweep, in
+// radians, for LikelyOpenPort to treat the reflection as rotating.
+const minOpenRotation = 10 * math.Pi / 180
+
+// LikelyOpenPort reports whether S11 looks like an unterminated port: the
+// reflection magnitude stays close to 1 across the sweep while the phase
+// rotates with frequency, as it does behind the cable or adapter of an
+// unconnected port, and is not parked near 180° (which would indicate a
+// short at the reference plane). A total reflection with constant phase,
+// such as an open standard at a calibrated reference plane, is not flagged.
+// It is a heuristic intended for "is the DUT connected?" warnings.
+func (d SweepData) LikelyOpenPort() bool {
+	if len(d.S11) < 2 {
+		return false
+	}
+
+	nearShort := 0
+	var phase, minPhase, maxPhase float64
+	for i, g := range d.S11 {
+		mag := cmplx.Abs(g)
+		if mag < 0.8 || mag > 1.2 {
+			return false
+		}
+		if math.Abs(cmplx.Phase(g)) > 0.75*math.Pi {
+			nearShort++
+		}
+		if i > 0 {
+			// Unwrap by taking the shortest step between neighbouring points
+			phase += cmplx.Phase(g / d.S11[i-1])
+			minPhase = math.Min(minPhase, phase)
+			maxPhase = math.Max(maxPhase, phase)
+		}
+	}
+	return nearShort < len(d.S11) && maxPhase-minPhase >= minOpenRotation
+}
+
+// NormalizeReflect divides S11 by the reference sweep's S11 point by point,
+// giving a quick one-port normalization against a measured reflect standard.
+// Both sweeps must share the same frequency grid. Reference points with a
+// magnitude below 1e-6 are clamped (keeping their phase) to avoid division
+// by zero. S21, S12 and S22 are copied unchanged.
+func (d SweepData) NormalizeReflect(ref SweepData) (SweepData, error) {
+	if len(d.S11) != len(d.Frequencies) || len(ref.S11) != len(ref.Frequencies) {
+		return SweepData{}, errors.New("frequency and S11 lengths differ")
+	}
+	if !sameGrid(d.Frequencies, ref.Frequencies, gridToleranceHz) {
+		return SweepData{}, fmt.Errorf("frequency grids differ (%d vs %d points)",
+			len(d.Frequencies), len(ref.Frequencies))
+	}
+
+	out := SweepData{
+		Frequencies: append([]float64(nil), d.Frequencies...),
+		S11:         make([]complex128, len(d.S11)),
+		S21:         append([]complex128(nil), d.S21...),
+		S12:         append([]complex128(nil), d.S12...),
+		S22:         append([]complex128(nil), d.S22...),
+		Z0:          d.Z0,
+	}
+	for i, g := range d.S11 {
+		r := ref.S11[i]
+		if cmplx.Abs(r) < minReferenceMag {
+			r = cmplx.Rect(minReferenceMag, cmplx.Phase(r))
+		}
+		out.S11[i] = g / r
+	}
+	return out, nil
+}
+
+// Equal reports whether two sweeps match within tolerances: frequencies may
+// differ by at most freqTol Hz and each complex S-parameter by at most
+// valueTol in magnitude. S21, S12 and S22 must each be either absent (nil
+// or empty) in both sweeps or present in both. Intended mainly for round-trip tests.
+func (d SweepData) Equal(other SweepData, freqTol, valueTol float64) bool {
+	if !sameGrid(d.Frequencies, other.Frequencies, freqTol) {
+		return false
+	}
+	return complexSlicesEqual(d.S11, other.S11, valueTol) &&
+		complexSlicesEqual(d.S21, other.S21, valueTol) &&
+		complexSlicesEqual(d.S12, other.S12, valueTol) &&
+		complexSlicesEqual(d.S22, other.S22, valueTol)
+}
+
+// complexSlicesEqual compares two complex slices element-wise within tol.
+func complexSlicesEqual(a, b []complex128, tol float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if cmplx.Abs(a[i]-b[i]) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+// SortByFrequency returns a copy of the sweep reordered so that Frequencies
+// is strictly ascending, moving S-parameter values with their frequency.
+// Points with a duplicate frequency keep only their first occurrence.
+// S-parameter slices whose length differs from Frequencies are copied as-is.
+func (d SweepData) SortByFrequency() SweepData {
+	idx := make([]int, len(d.Frequencies))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return d.Frequencies[idx[a]] < d.Frequencies[idx[b]]
+	})
+
+	// Drop duplicate frequencies, keeping the first occurrence
+	kept := idx[:0]
+	for _, i := range idx {
+		if len(kept) > 0 && d.Frequencies[kept[len(kept)-1]] == d.Frequencies[i] {
+			continue
+		}
+		kept = append(kept, i)
+	}
+
+	out := d
+	out.Frequencies = make([]float64, len(kept))
+	for j, i := range kept {
+		out.Frequencies[j] = d.Frequencies[i]
+	}
+	out.S11 = reorderComplex(d.S11, kept, len(d.Frequencies))
+	out.S21 = reorderComplex(d.S21, kept, len(d.Frequencies))
+	out.S12 = reorderComplex(d.S12, kept, len(d.Frequencies))
+	out.S22 = reorderComplex(d.S22, kept, len(d.Frequencies))
+	return out
+}
+
+// reorderComplex returns v permuted by idx when it has n elements, or an
+// unmodified copy otherwise.
+func reorderComplex(v []complex128, idx []int, n int) []complex128 {
+	if len(v) != n {
+		return append([]complex128(nil), v...)
+	}
+	out := make([]complex128, len(idx))
+	for j, i := range idx {
+		out[j] = v[i]
+	}
+	return out
+}
+
+// Completeness returns the fraction of expected points present in the sweep,
+// len(S11)/expected clamped to 1, as a data-quality indicator for sweeps
+// that may have come back short. It returns 0 when expected is not positive.
+func (d SweepData) Completeness(expected int) float64 {
+	if expected <= 0 {
+		return 0
+	}
+	return math.Min(float64(len(d.S11))/float64(expected), 1)
+}
+
+// ShiftFrequency returns a copy of the sweep with offsetHz added to every
+// frequency, for post-hoc correction of a device whose frequency axis is off.
+func (d SweepData) ShiftFrequency(offsetHz float64) SweepData {
+	out := d
+	out.Frequencies = make([]float64, len(d.Frequencies))
+	for i, f := range d.Frequencies {
+		out.Frequencies[i] = f + offsetHz
+	}
+	out.S11 = append([]complex128(nil), d.S11...)
+	out.S21 = append([]complex128(nil), d.S21...)
+	out.S12 = append([]complex128(nil), d.S12...)
+	out.S22 = append([]complex128(nil), d.S22...)
+	return out
+}
+
+// Smooth returns a copy of the sweep with a centered moving average of
+// window points applied to each complex S-parameter trace, leaving the
+// frequencies untouched. Near the ends the average covers only the points
+// that exist, so the trace keeps its length. An even window is widened to
+// the next odd size; a window of 1 or less returns an unsmoothed copy.
+func (d SweepData) Smooth(window int) SweepData {
+	half := window / 2
+	if window < 1 {
+		half = 0
+	}
+	out := d
+	out.Frequencies = append([]float64(nil), d.Frequencies...)
+	out.S11 = smoothComplex(d.S11, half)
+	out.S21 = smoothComplex(d.S21, half)
+	out.S12 = smoothComplex(d.S12, half)
+	out.S22 = smoothComplex(d.S22, half)
+	return out
+}
+
+// smoothComplex returns the moving average of v over half points on each
+// side, truncated at the ends.
+func smoothComplex(v []complex128, half int) []complex128 {
+	if v == nil {
+		return nil
+	}
+	out := make([]complex128, len(v))
+	for i := range v {
+		lo, hi := max(i-half, 0), min(i+half, len(v)-1)
+		var sum complex128
+		for _, x := range v[lo : hi+1] {
+			sum += x
+		}
+		out[i] = sum / complex(float64(hi-lo+1), 0)
+	}
+	return out
+}
+
+// EstimateFrequencyOffset estimates how far this sweep's frequency axis is
+// displaced from ref's by cross-correlating their S11 magnitude (dB) traces.
+// A feature at frequency f in ref appears at f+offset in d, so
+// d.ShiftFrequency(-offset) aligns the two. Lags up to a quarter of the
+// reference span are searched at the reference point spacing and refined to
+// sub-point resolution by parabolic interpolation. It returns 0 when either
+// sweep has fewer than three points.
+func (d SweepData) EstimateFrequencyOffset(ref SweepData) float64 {
+	if len(d.S11) < 3 || len(d.S11) != len(d.Frequencies) ||
+		len(ref.S11) < 3 || len(ref.S11) != len(ref.Frequencies) {
+		return 0
+	}
+	if !sort.Float64sAreSorted(d.Frequencies) {
+		d = d.SortByFrequency()
+	}
+	if !sort.Float64sAreSorted(ref.Frequencies) {
+		ref = ref.SortByFrequency()
+	}
+
+	refDB := make([]float64, len(ref.S11))
+	for i, g := range ref.S11 {
+		refDB[i] = magnitudeDB(g)
+	}
+	dDB := make([]float64, len(d.S11))
+	for i, g := range d.S11 {
+		dDB[i] = magnitudeDB(g)
+	}
+
+	n := len(ref.Frequencies)
+	step := (ref.Frequencies[n-1] - ref.Frequencies[0]) / float64(n-1)
+	if step <= 0 {
+		return 0
+	}
+	maxLag := n / 4
+
+	// score returns the normalized correlation of ref with d shifted by lag steps
+	score := func(lag int) float64 {
+		var a, b []float64
+		for i, f := range ref.Frequencies {
+			j, t, ok := bracket(d.Frequencies, f+float64(lag)*step)
+			if !ok {
+				continue
+			}
+			v := dDB[j]
+			if j+1 < len(dDB) {
+				v += t * (dDB[j+1] - dDB[j])
+			}
+			a = append(a, refDB[i])
+			b = append(b, v)
+		}
+		return correlation(a, b)
+	}
+
+	bestLag, best := 0, math.Inf(-1)
+	scores := make(map[int]float64, 2*maxLag+1)
+	for lag := -maxLag; lag <= maxLag; lag++ {
+		s := score(lag)
+		scores[lag] = s
+		if s > best {
+			bestLag, best = lag, s
+		}
+	}
+
+	// Parabolic refinement around the peak
+	offset := float64(bestLag)
+	if lo, okLo := scores[bestLag-1]; okLo {
+		if hi, okHi := scores[bestLag+1]; okHi {
+			if den := lo - 2*best + hi; den < 0 {
+				offset += 0.5 * (lo - hi) / den
+			}
+		}
+	}
+	return offset * step
+}
+
+// minShortMagnitude is the smallest mean |S11| ReferencePlaneOffset accepts
+// as a short; a lower value means the DUT is not a reflective standard.
+const minShortMagnitude = 0.5
+
+// ReferencePlaneOffset assumes the DUT is a short (Γ ≈ −1) and estimates the
+// one-way electrical delay between the calibrated reference plane and the
+// short from the slope of the residual phase of −S11, which for a delay τ
+// is −4π·f·τ. A least-squares line (with intercept) is fitted to the
+// unwrapped phase. A positive result means the short sits beyond the
+// reference plane, as when electrical delay is under-compensated; a
+// correctly set reference plane gives a value near zero. It returns an error
+// for fewer than two points or when S11 is not predominantly reflective.
+func (d SweepData) ReferencePlaneOffset() (float64, error) {
+	n := len(d.S11)
+	if n < 2 || n != len(d.Frequencies) {
+		return 0, fmt.Errorf("need at least 2 points with S11, got %d", n)
+	}
+	if !sort.Float64sAreSorted(d.Frequencies) {
+		d = d.SortByFrequency()
+		n = len(d.S11)
+	}
+
+	var meanMag float64
+	phase := make([]float64, n)
+	for i, g := range d.S11 {
+		meanMag += cmplx.Abs(g)
+		phase[i] = cmplx.Phase(-g)
+	}
+	if meanMag/float64(n) < minShortMagnitude {
+		return 0, errors.New("S11 is not reflective enough to be a short")
+	}
+	phase = UnwrapPhase(phase)
+
+	var meanF, meanP float64
+	for i := range phase {
+		meanF += d.Frequencies[i]
+		meanP += phase[i]
+	}
+	meanF /= float64(n)
+	meanP /= float64(n)
+	var sff, sfp float64
+	for i := range phase {
+		df := d.Frequencies[i] - meanF
+		sff += df * df
+		sfp += df * (phase[i] - meanP)
+	}
+	if sff == 0 {
+		return 0, errors.New("sweep spans a single frequency")
+	}
+	return -(sfp / sff) / (4 * math.Pi), nil
+}
+
+// UnwrapPhase returns a copy of p, a sequence of phases in radians, with a
+// multiple of 2π added wherever consecutive samples jump by more than π, so
+// that the result is continuous.
+func UnwrapPhase(p []float64) []float64 {
+	out := make([]float64, len(p))
+	offset := 0.0
+	for i, v := range p {
+		if i > 0 {
+			diff := v + offset - out[i-1]
+			offset -= 2 * math.Pi * math.Round(diff/(2*math.Pi))
+		}
+		out[i] = v + offset
+	}
+	return out
+}
+
+// S11PhaseDegrees returns the unwrapped S11 phase in degrees.
+func (d SweepData) S11PhaseDegrees() []float64 {
+	return unwrappedDegrees(d.S11)
+}
+
+// S21PhaseDegrees returns the unwrapped S21 phase in degrees, or an empty
+// slice when the sweep has no S21.
+func (d SweepData) S21PhaseDegrees() []float64 {
+	return unwrappedDegrees(d.S21)
+}
+
+// unwrappedDegrees returns the unwrapped phase of v in degrees.
+func unwrappedDegrees(v []complex128) []float64 {
+	p := make([]float64, len(v))
+	for i, x := range v {
+		p[i] = cmplx.Phase(x)
+	}
+	p = UnwrapPhase(p)
+	for i := range p {
+		p[i] *= 180 / math.Pi
+	}
+	return p
+}
+
+// correlation returns the Pearson correlation of a and b, or -Inf when it is
+// undefined (fewer than two samples or zero variance).
+func correlation(a, b []float64) float64 {
+	if len(a) < 2 {
+		return math.Inf(-1)
+	}
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(len(a))
+	meanB /= float64(len(b))
+
+	var cov, varA, varB float64
+	for i := range a {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return math.Inf(-1)
+	}
+	return cov / math.Sqrt(varA*varB)
+}
@@ -0,0 +1,255 @@
+package nanovna
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestSweepData_LikelyOpenPort(t *testing.T) {
+	freqs := []float64{1e6, 2e6, 3e6, 4e6}
+	rotating := make([]complex128, len(freqs))
+	for i := range rotating {
+		rotating[i] = cmplx.Rect(0.98, -0.8*float64(i))
+	}
+	tests := []struct {
+		name string
+		s11  []complex128
+		want bool
+	}{
+		{"open with cable", rotating, true},
+		{"matched load", []complex128{0.01, 0.02, 0.01, 0.03}, false},
+		{"short at plane", []complex128{-1, -0.99, -1, -0.98}, false},
+		{"open at plane", []complex128{1, 0.99, 1, 0.98}, false},
+		{"single point", []complex128{1}, false},
+	}
+	for _, tc := range tests {
+		d := SweepData{Frequencies: freqs[:len(tc.s11)], S11: tc.s11}
+		if got := d.LikelyOpenPort(); got != tc.want {
+			t.Errorf("%s: LikelyOpenPort() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSweepData_NormalizeReflect(t *testing.T) {
+	ref := SweepData{Frequencies: []float64{1e6, 2e6}, S11: []complex128{complex(0, 0.5), 0}}
+	meas := SweepData{Frequencies: []float64{1e6, 2e6}, S11: []complex128{complex(0, 0.25), 0.5}, Z0: 75}
+	got, err := meas.NormalizeReflect(ref)
+	if err != nil {
+		t.Fatalf("NormalizeReflect failed: %v", err)
+	}
+	if got.Z0 != 75 {
+		t.Errorf("Z0 = %v, want 75", got.Z0)
+	}
+	if cmplx.Abs(got.S11[0]-0.5) > 1e-12 {
+		t.Errorf("S11[0] = %v, want 0.5", got.S11[0])
+	}
+	if cmplx.IsInf(got.S11[1]) || cmplx.IsNaN(got.S11[1]) {
+		t.Errorf("zero reference should be clamped, got %v", got.S11[1])
+	}
+
+	other := SweepData{Frequencies: []float64{1e6, 3e6}, S11: []complex128{1, 1}}
+	if _, err := meas.NormalizeReflect(other); err == nil {
+		t.Error("Expected error for mismatched grids")
+	}
+}
+
+func TestSweepData_Equal(t *testing.T) {
+	a := SweepData{Frequencies: []float64{1e6, 2e6}, S11: []complex128{0.1, complex(0.2, 0.1)}}
+	b := SweepData{Frequencies: []float64{1e6 + 0.5, 2e6}, S11: []complex128{0.1 + 1e-9, complex(0.2, 0.1)}}
+	if !a.Equal(b, 1, 1e-6) {
+		t.Error("sweeps within tolerance should be equal")
+	}
+	if a.Equal(b, 0.1, 1e-6) {
+		t.Error("frequency difference beyond tolerance should not be equal")
+	}
+	withS21 := b
+	withS21.S21 = []complex128{0, 0}
+	if a.Equal(withS21, 1, 1e-6) {
+		t.Error("nil S21 should not equal present S21")
+	}
+}
+
+func TestSweepData_SortByFrequency_Descending(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{3e6, 2e6, 2e6, 1e6},
+		S11:         []complex128{3, 2, 99, 1},
+		S21:         []complex128{30, 20, 99, 10},
+	}
+	got := d.SortByFrequency()
+	want := SweepData{
+		Frequencies: []float64{1e6, 2e6, 3e6},
+		S11:         []complex128{1, 2, 3},
+		S21:         []complex128{10, 20, 30},
+	}
+	if !got.Equal(want, 0, 0) {
+		t.Errorf("SortByFrequency() = %+v, want %+v", got, want)
+	}
+	if d.Frequencies[0] != 3e6 {
+		t.Error("SortByFrequency must not modify the receiver")
+	}
+}
+
+// resonantSweep returns a sweep with a single S11 dip centred on centerHz.
+func resonantSweep(startHz, stepHz, centerHz float64, n int) SweepData {
+	d := SweepData{}
+	for i := 0; i < n; i++ {
+		f := startHz + float64(i)*stepHz
+		x := (f - centerHz) / (20 * stepHz)
+		d.Frequencies = append(d.Frequencies, f)
+		d.S11 = append(d.S11, complex(1-0.9/(1+x*x), 0))
+	}
+	return d
+}
+
+func TestSweepData_EstimateFrequencyOffset(t *testing.T) {
+	ref := resonantSweep(10e6, 10e3, 11e6, 201)
+	shifted := resonantSweep(10e6, 10e3, 11e6+25e3, 201)
+	offset := shifted.EstimateFrequencyOffset(ref)
+	if math.Abs(offset-25e3) > 2e3 {
+		t.Errorf("EstimateFrequencyOffset = %v, want ~25000", offset)
+	}
+
+	aligned := shifted.ShiftFrequency(-offset)
+	if math.Abs(aligned.Frequencies[0]-(10e6-offset)) > 1e-6 {
+		t.Errorf("ShiftFrequency start = %v", aligned.Frequencies[0])
+	}
+	if shifted.Frequencies[0] != 10e6 {
+		t.Error("ShiftFrequency must not modify the receiver")
+	}
+}
+
+func TestSweepData_Smooth(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1, 2, 3, 4, 5},
+		S11:         []complex128{0, 3, 0, 3i, 0},
+		S21:         []complex128{1, 1, 1, 1, 1},
+	}
+	got := d.Smooth(3)
+	want := []complex128{1.5, 1, 1 + 1i, 1i, 1.5i}
+	for i := range want {
+		if cmplx.Abs(got.S11[i]-want[i]) > 1e-12 {
+			t.Errorf("S11[%d] = %v, want %v", i, got.S11[i], want[i])
+		}
+		if got.S21[i] != 1 {
+			t.Errorf("S21[%d] = %v, want 1", i, got.S21[i])
+		}
+	}
+	if got.S12 != nil || got.Frequencies[4] != 5 {
+		t.Errorf("unexpected S12 %v or frequencies %v", got.S12, got.Frequencies)
+	}
+	if d.S11[1] != 3 {
+		t.Error("Smooth modified its receiver")
+	}
+	if even := d.Smooth(2); cmplx.Abs(even.S11[1]-1) > 1e-12 {
+		t.Errorf("even window should widen to 3, S11[1] = %v", even.S11[1])
+	}
+	if plain := d.Smooth(1); plain.S11[1] != 3 {
+		t.Errorf("window 1 should not smooth, S11[1] = %v", plain.S11[1])
+	}
+}
+
+func TestSweepData_ReverseTraces(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 2e6},
+		S11:         []complex128{0.5, 0.5},
+		S12:         []complex128{0.1, 0.3},
+		S22:         []complex128{0.2i, 0.4i},
+	}
+
+	shifted := d.ShiftFrequency(1000)
+	if len(shifted.S12) != 2 || shifted.S22[1] != 0.4i {
+		t.Errorf("ShiftFrequency dropped S12/S22: %v/%v", shifted.S12, shifted.S22)
+	}
+	mid, err := d.InterpolateTo([]float64{1.5e6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmplx.Abs(mid.S12[0]-0.2) > 1e-12 || cmplx.Abs(mid.S22[0]-0.3i) > 1e-12 || mid.S21 != nil {
+		t.Errorf("InterpolateTo = S12 %v, S22 %v, S21 %v", mid.S12, mid.S22, mid.S21)
+	}
+	norm, err := d.NormalizeReflect(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(norm.S12) != 2 || norm.S22[0] != 0.2i {
+		t.Errorf("NormalizeReflect dropped S12/S22: %v/%v", norm.S12, norm.S22)
+	}
+
+	other := d.ShiftFrequency(0)
+	other.S22[1] = 0
+	if d.Equal(other, 1, 1e-9) {
+		t.Error("sweeps differing only in S22 should not be equal")
+	}
+	other.S22 = nil
+	if d.Equal(other, 1, 1e-9) {
+		t.Error("sweeps differing in S22 presence should not be equal")
+	}
+}
+
+func TestSweepData_Completeness(t *testing.T) {
+	d := SweepData{S11: make([]complex128, 92)}
+	tests := []struct {
+		expected int
+		want     float64
+	}{
+		{100, 0.92},
+		{92, 1},
+		{50, 1},
+		{0, 0},
+	}
+	for _, tc := range tests {
+		if got := d.Completeness(tc.expected); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("Completeness(%d) = %v, want %v", tc.expected, got, tc.want)
+		}
+	}
+}
+
+func TestSweepData_ReferencePlaneOffset(t *testing.T) {
+	const tau = 50e-12
+	var d SweepData
+	for i := 0; i < 101; i++ {
+		f := 1e6 + float64(i)*30e6
+		d.Frequencies = append(d.Frequencies, f)
+		d.S11 = append(d.S11, -cmplx.Rect(0.99, -4*math.Pi*f*tau))
+	}
+	got, err := d.ReferencePlaneOffset()
+	if err != nil {
+		t.Fatalf("ReferencePlaneOffset failed: %v", err)
+	}
+	if math.Abs(got-tau) > 1e-15 {
+		t.Errorf("ReferencePlaneOffset() = %g, want %g", got, tau)
+	}
+
+	load := SweepData{Frequencies: []float64{1e6, 2e6}, S11: []complex128{0.01, 0.02}}
+	if _, err := load.ReferencePlaneOffset(); err == nil {
+		t.Error("Expected error for a matched load")
+	}
+}
+
+func TestUnwrapPhase(t *testing.T) {
+	got := UnwrapPhase([]float64{3, -3, -1, 3})
+	want := []float64{3, 2*math.Pi - 3, 2*math.Pi - 1, 3}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Fatalf("UnwrapPhase = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSweepData_PhaseDegrees(t *testing.T) {
+	// Phase steps of −100° wrap past −180° on the third point
+	d := SweepData{S11: make([]complex128, 4)}
+	for i := range d.S11 {
+		d.S11[i] = cmplx.Rect(1, -float64(i)*100*math.Pi/180)
+	}
+	got := d.S11PhaseDegrees()
+	for i, p := range got {
+		if math.Abs(p+float64(i)*100) > 1e-9 {
+			t.Fatalf("S11PhaseDegrees = %v, want [0 -100 -200 -300]", got)
+		}
+	}
+	if p := d.S21PhaseDegrees(); len(p) != 0 {
+		t.Errorf("S21PhaseDegrees without S21 = %v, want empty", p)
+	}
+}
@@ -0,0 +1,231 @@
+package nanovna
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// archiveVersion is the current sweep archive format version.
+const archiveVersion = 1
+
+// Measurement is a sweep together with the context it was taken in.
+type Measurement struct {
+	Config    SweepConfig
+	Timestamp time.Time
+	Info      DeviceInfo
+	Data      SweepData
+	// Metadata holds caller-supplied experimental context such as
+	// temperature, operator or DUT identifier.
+	Metadata map[string]string `json:",omitempty"`
+}
+
+// MeasureWithMetadata applies cfg, runs a sweep and returns it as a
+// Measurement stamped with the current time, the device information and a
+// copy of meta, ready to be stored with WriteSweepArchive. Device
+// information is best effort: if it cannot be read the Info field is left
+// empty rather than failing the measurement.
+func (d *Device) MeasureWithMetadata(cfg SweepConfig, meta map[string]string) (Measurement, error) {
+	return d.MeasureWithMetadataContext(context.Background(), cfg, meta)
+}
+
+// MeasureWithMetadataContext is like MeasureWithMetadata but stops waiting
+// for the device once ctx is done.
+func (d *Device) MeasureWithMetadataContext(ctx context.Context, cfg SweepConfig, meta map[string]string) (Measurement, error) {
+	if err := d.SetSweepConfigModeContext(ctx, cfg.StartHz, cfg.StopHz, cfg.Points, cfg.Mode); err != nil {
+		return Measurement{}, err
+	}
+	data, err := d.RunSweepContext(ctx)
+	if err != nil {
+		return Measurement{}, err
+	}
+	m := Measurement{Config: cfg, Timestamp: time.Now(), Data: data}
+	if info, err := d.GetInfoContext(ctx); err == nil {
+		m.Info = info
+	}
+	if len(meta) > 0 {
+		m.Metadata = make(map[string]string, len(meta))
+		for k, v := range meta {
+			m.Metadata[k] = v
+		}
+	}
+	return m, nil
+}
+
+// sweepArchive is the on-disk layout written by WriteSweepArchive.
+type sweepArchive struct {
+	Version      int           `json:"version"`
+	Measurements []Measurement `json:"measurements"`
+}
+
+// WriteSweepArchive writes a series of measurements, with their sweep
+// configurations, timestamps and device information, to w as a single
+// versioned JSON document. Read it back with ReadSweepArchive.
+func WriteSweepArchive(w io.Writer, sweeps []Measurement) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sweepArchive{Version: archiveVersion, Measurements: sweeps})
+}
+
+// ReadSweepArchive reads measurements written by WriteSweepArchive.
+func ReadSweepArchive(r io.Reader) ([]Measurement, error) {
+	var a sweepArchive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, fmt.Errorf("failed to decode sweep archive: %v", err)
+	}
+	if a.Version != archiveVersion {
+		return nil, fmt.Errorf("unsupported sweep archive version %d", a.Version)
+	}
+	return a.Measurements, nil
+}
+
+// sweepDataJSON is the JSON form of SweepData; complex values are encoded
+// as [real, imaginary] pairs since encoding/json has no complex support.
+type sweepDataJSON struct {
+	Frequencies []float64    `json:"frequencies"`
+	S11         [][2]float64 `json:"s11"`
+	S21         [][2]float64 `json:"s21,omitempty"`
+	S12         [][2]float64 `json:"s12,omitempty"`
+	S22         [][2]float64 `json:"s22,omitempty"`
+	Z0          float64      `json:"z0,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d SweepData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sweepDataJSON{
+		Frequencies: d.Frequencies,
+		S11:         complexToPairs(d.S11),
+		S21:         complexToPairs(d.S21),
+		S12:         complexToPairs(d.S12),
+		S22:         complexToPairs(d.S22),
+		Z0:          d.Z0,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *SweepData) UnmarshalJSON(b []byte) error {
+	var j sweepDataJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*d = SweepData{
+		Frequencies: j.Frequencies,
+		S11:         pairsToComplex(j.S11),
+		S21:         pairsToComplex(j.S21),
+		S12:         pairsToComplex(j.S12),
+		S22:         pairsToComplex(j.S22),
+		Z0:          j.Z0,
+	}
+	return nil
+}
+
+// complexToPairs converts complex values to [real, imaginary] pairs.
+func complexToPairs(v []complex128) [][2]float64 {
+	if v == nil {
+		return nil
+	}
+	out := make([][2]float64, len(v))
+	for i, c := range v {
+		out[i] = [2]float64{real(c), imag(c)}
+	}
+	return out
+}
+
+// pairsToComplex converts [real, imaginary] pairs back to complex values.
+func pairsToComplex(p [][2]float64) []complex128 {
+	if p == nil {
+		return nil
+	}
+	out := make([]complex128, len(p))
+	for i, v := range p {
+		out[i] = complex(v[0], v[1])
+	}
+	return out
+}
+
+// calibrationFileVersion is the current calibration file format version.
+const calibrationFileVersion = 1
+
+// calibrationFile is the on-disk layout written by CalibrationData.Save.
+// Error terms are stored as [real, imaginary] pairs like sweep data.
+type calibrationFile struct {
+	Version      int          `json:"version"`
+	Standards    []string     `json:"standards,omitempty"`
+	Frequencies  []float64    `json:"frequencies"`
+	Directivity  [][2]float64 `json:"directivity"`
+	SourceMatch  [][2]float64 `json:"source_match"`
+	Reflection   [][2]float64 `json:"reflection_tracking"`
+	Transmission [][2]float64 `json:"transmission_tracking,omitempty"`
+	Isolation    [][2]float64 `json:"isolation,omitempty"`
+}
+
+// Save writes the calibration to w as a versioned JSON document recording
+// the frequency grid, the standards it was solved from (when Standards is
+// set, as by CalSession.Finish) and every error term, so a fixture's
+// calibration can be kept on disk and reapplied in later sessions with
+// LoadCalibration and ApplyCalibration.
+func (c CalibrationData) Save(w io.Writer) error {
+	if !c.Valid() {
+		return errors.New("calibration is not valid")
+	}
+	var standards []string
+	for _, std := range c.Standards {
+		standards = append(standards, std.String())
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(calibrationFile{
+		Version:      calibrationFileVersion,
+		Standards:    standards,
+		Frequencies:  c.Frequencies,
+		Directivity:  complexToPairs(c.Directivity),
+		SourceMatch:  complexToPairs(c.SourceMatch),
+		Reflection:   complexToPairs(c.ReflectionTracking),
+		Transmission: complexToPairs(c.TransmissionTracking),
+		Isolation:    complexToPairs(c.Isolation),
+	})
+}
+
+// LoadCalibration reads a calibration written by CalibrationData.Save and
+// checks that it is complete.
+func LoadCalibration(r io.Reader) (CalibrationData, error) {
+	var f calibrationFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return CalibrationData{}, fmt.Errorf("failed to decode calibration: %v", err)
+	}
+	if f.Version != calibrationFileVersion {
+		return CalibrationData{}, fmt.Errorf("unsupported calibration file version %d", f.Version)
+	}
+	c := CalibrationData{
+		Frequencies:          f.Frequencies,
+		Directivity:          pairsToComplex(f.Directivity),
+		SourceMatch:          pairsToComplex(f.SourceMatch),
+		ReflectionTracking:   pairsToComplex(f.Reflection),
+		TransmissionTracking: pairsToComplex(f.Transmission),
+		Isolation:            pairsToComplex(f.Isolation),
+	}
+	if !c.Valid() {
+		return CalibrationData{}, errors.New("calibration file has missing or mismatched error terms")
+	}
+	for _, name := range f.Standards {
+		std, ok := parseCalStandard(name)
+		if !ok {
+			return CalibrationData{}, fmt.Errorf("unknown calibration standard %q", name)
+		}
+		c.Standards = append(c.Standards, std)
+	}
+	return c, nil
+}
+
+// parseCalStandard returns the standard whose String is name.
+func parseCalStandard(name string) (CalStandard, bool) {
+	for _, std := range []CalStandard{CalShort, CalOpen, CalLoad, CalThru} {
+		if std.String() == name {
+			return std, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,132 @@
+package nanovna
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSweepArchive_RoundTrip(t *testing.T) {
+	in := []Measurement{
+		{
+			Config:    SweepConfig{StartHz: 1000000, StopHz: 2000000, Points: 2},
+			Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Info:      DeviceInfo{Model: "NanoVNA-H", Firmware: "v1.2.0"},
+			Metadata:  map[string]string{"temperature": "23.5", "dut": "A7"},
+			Data: SweepData{
+				Frequencies: []float64{1e6, 2e6},
+				S11:         []complex128{complex(0.1, -0.2), complex(0.3, 0.4)},
+			},
+		},
+		{
+			Data: SweepData{
+				Frequencies: []float64{3e6},
+				S11:         []complex128{0.5},
+				S21:         []complex128{complex(0, 1)},
+				Z0:          75,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSweepArchive(&buf, in); err != nil {
+		t.Fatalf("WriteSweepArchive failed: %v", err)
+	}
+	out, err := ReadSweepArchive(&buf)
+	if err != nil {
+		t.Fatalf("ReadSweepArchive failed: %v", err)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("got %d measurements, want %d", len(out), len(in))
+	}
+	for i := range in {
+		if !out[i].Data.Equal(in[i].Data, 0, 0) || out[i].Data.Z0 != in[i].Data.Z0 {
+			t.Errorf("measurement %d data mismatch: %+v", i, out[i].Data)
+		}
+		if out[i].Config != in[i].Config || !out[i].Timestamp.Equal(in[i].Timestamp) || out[i].Info != in[i].Info {
+			t.Errorf("measurement %d metadata mismatch: %+v", i, out[i])
+		}
+		if len(out[i].Metadata) != len(in[i].Metadata) || out[i].Metadata["temperature"] != in[i].Metadata["temperature"] {
+			t.Errorf("measurement %d Metadata = %v, want %v", i, out[i].Metadata, in[i].Metadata)
+		}
+	}
+}
+
+func TestReadSweepArchive_BadVersion(t *testing.T) {
+	if _, err := ReadSweepArchive(strings.NewReader(`{"version": 99}`)); err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+}
+
+func TestDevice_MeasureWithMetadata(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 2000000 2": shellResponse("sweep 1000000 2000000 2"),
+		"frequencies":             shellResponse("frequencies", "1000000", "2000000"),
+		"data 0":                  shellResponse("data 0", "0.1 0.2", "0.3 0.4"),
+		"info":                    shellResponse("info", "NanoVNA-H"),
+	}}
+	dev, _ := Open("COM1", port)
+	meta := map[string]string{"temperature": "-10"}
+	cfg := SweepConfig{StartHz: 1000000, StopHz: 2000000, Points: 2}
+	m, err := dev.MeasureWithMetadata(cfg, meta)
+	if err != nil {
+		t.Fatalf("MeasureWithMetadata failed: %v", err)
+	}
+	if m.Config != cfg || len(m.Data.S11) != 2 || m.Timestamp.IsZero() {
+		t.Errorf("unexpected measurement %+v", m)
+	}
+	meta["temperature"] = "25"
+	if m.Metadata["temperature"] != "-10" {
+		t.Errorf("Metadata should be copied, got %v", m.Metadata)
+	}
+}
+
+func TestCalibrationData_SaveLoad(t *testing.T) {
+	cal := CalibrationData{
+		Frequencies:          []float64{1e6, 2e6},
+		Directivity:          []complex128{complex(0.01, -0.02), 0.01},
+		SourceMatch:          []complex128{0.05, complex(0, 0.05)},
+		ReflectionTracking:   []complex128{1, complex(0.9, 0.1)},
+		TransmissionTracking: []complex128{0.8, 0.7},
+		Standards:            []CalStandard{CalShort, CalOpen, CalLoad, CalThru},
+	}
+	var buf bytes.Buffer
+	if err := cal.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"Thru"`) {
+		t.Errorf("saved calibration should list the Thru standard:\n%s", buf.String())
+	}
+
+	got, err := LoadCalibration(&buf)
+	if err != nil {
+		t.Fatalf("LoadCalibration failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, cal) {
+		t.Errorf("round trip = %+v, want %+v", got, cal)
+	}
+
+	if err := (CalibrationData{}).Save(&buf); err == nil {
+		t.Error("Expected error saving an invalid calibration")
+	}
+	if _, err := LoadCalibration(strings.NewReader(`{"version": 2}`)); err == nil {
+		t.Error("Expected error for an unknown version")
+	}
+
+	cal.Standards = nil
+	buf.Reset()
+	if err := cal.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "standards") {
+		t.Errorf("calibration without known standards should not list any:\n%s", buf.String())
+	}
+	if got, err := LoadCalibration(&buf); err != nil || got.Standards != nil {
+		t.Errorf("LoadCalibration = %v standards, %v", got.Standards, err)
+	}
+	if _, err := LoadCalibration(strings.NewReader(`{"version": 1, "frequencies": [1]}`)); err == nil {
+		t.Error("Expected error for missing error terms")
+	}
+}
@@ -0,0 +1,81 @@
+package nanovna
+
+import (
+	"math"
+	"testing"
+)
+
+// sequencePort answers the nth "data 0" request with the nth response.
+type sequencePort struct {
+	ScriptedSerialPort
+	Data  []string
+	calls int
+}
+
+func (s *sequencePort) Write(p []byte) (int, error) {
+	if string(p) == "data 0\r" && s.calls < len(s.Data) {
+		s.Responses["data 0"] = shellResponse("data 0", s.Data[s.calls])
+		s.calls++
+	}
+	return s.ScriptedSerialPort.Write(p)
+}
+
+func TestDevice_RunSweepWithUncertainty(t *testing.T) {
+	port := &sequencePort{
+		ScriptedSerialPort: ScriptedSerialPort{Responses: map[string]string{
+			"frequencies": shellResponse("frequencies", "1000000"),
+		}},
+		Data: []string{"0.1 0", "0.3 0"},
+	}
+	dev, _ := Open("COM1", port)
+	mean, stdErr, err := dev.RunSweepWithUncertainty(2)
+	if err != nil {
+		t.Fatalf("RunSweepWithUncertainty failed: %v", err)
+	}
+	if math.Abs(real(mean.S11[0])-0.2) > 1e-12 {
+		t.Errorf("mean S11 = %v, want 0.2", mean.S11[0])
+	}
+	// Sample std dev of {0.1, 0.3} is 0.1·√2; divided by √2 gives 0.1
+	if len(stdErr) != 1 || math.Abs(stdErr[0]-0.1) > 1e-12 {
+		t.Errorf("standard error = %v, want [0.1]", stdErr)
+	}
+
+	if _, _, err := dev.RunSweepWithUncertainty(1); err == nil {
+		t.Error("Expected error for a single sweep")
+	}
+}
+
+func TestDevice_RunSweepAveraged(t *testing.T) {
+	port := &sequencePort{
+		ScriptedSerialPort: ScriptedSerialPort{Responses: map[string]string{
+			"frequencies": shellResponse("frequencies", "1000000"),
+		}},
+		Data: []string{"0.1 0.2", "0.3 0", "0.2 0.1"},
+	}
+	dev, _ := Open("COM1", port)
+	mean, err := dev.RunSweepAveraged(3)
+	if err != nil {
+		t.Fatalf("RunSweepAveraged failed: %v", err)
+	}
+	if len(mean.S11) != 1 || math.Abs(real(mean.S11[0])-0.2) > 1e-12 || math.Abs(imag(mean.S11[0])-0.1) > 1e-12 {
+		t.Errorf("mean S11 = %v, want (0.2+0.1i)", mean.S11)
+	}
+
+	if _, err := dev.RunSweepAveraged(0); err == nil {
+		t.Error("Expected error for zero sweeps")
+	}
+}
+
+func TestMeanSweep_ReverseTraces(t *testing.T) {
+	sweeps := []SweepData{
+		{Frequencies: []float64{1}, S11: []complex128{0}, S12: []complex128{1}, S22: []complex128{2i}},
+		{Frequencies: []float64{1}, S11: []complex128{0}, S12: []complex128{3}},
+	}
+	mean := meanSweep(sweeps)
+	if len(mean.S12) != 1 || mean.S12[0] != 2 {
+		t.Errorf("S12 = %v, want [2]", mean.S12)
+	}
+	if mean.S22 != nil {
+		t.Errorf("S22 missing from one sweep should be nil, got %v", mean.S22)
+	}
+}
@@ -0,0 +1,167 @@
+package nanovna
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// SetByteOrder sets the byte order used by every binary decode path (binary
+// sweep frames, raw scan samples). The default is little-endian, which is
+// what all known firmware uses:
+//
+//   - NanoVNA v2, v2 Plus, v2 Plus4, SAA2 and LiteVNA binary frames: little-endian
+//   - TinySA scanraw samples: little-endian
+//
+// Override it only for firmware builds that deviate; a wrong setting yields
+// garbage complex values rather than an error. Passing nil restores the default.
+func (d *Device) SetByteOrder(order binary.ByteOrder) {
+	d.byteOrder = order
+}
+
+// GetByteOrder returns the byte order used for binary decoding.
+func (d *Device) GetByteOrder() binary.ByteOrder {
+	if d.byteOrder == nil {
+		return binary.LittleEndian
+	}
+	return d.byteOrder
+}
+
+// scan_bin output mask bits selecting the fields of each binary record.
+const (
+	scanBinFrequency = 1 << 0 // uint32 frequency in Hz
+	scanBinS11       = 1 << 1 // float32 real and imaginary S11
+	scanBinS21       = 1 << 2 // float32 real and imaginary S21
+	scanBinBinary    = 1 << 7 // Binary rather than text output
+
+	scanBinMask       = scanBinBinary | scanBinFrequency | scanBinS11 | scanBinS21
+	scanBinHeaderSize = 4
+	scanBinRecordSize = 4 + 8 + 8
+)
+
+// binaryScanTimeout bounds a whole scan_bin transaction.
+const binaryScanTimeout = 30 * time.Second
+
+// RunSweepBinary measures the configured sweep with the firmware's binary
+// scan_bin command, which returns packed little-endian records instead of
+// text and is much faster for long sweeps. The reply is a 4-byte header
+// (uint16 mask, uint16 points) followed by one record per point: a uint32
+// frequency and float32 real/imaginary pairs for S11 and S21. Variants
+// without HasBinaryScan, and firmware that rejects the command, are swept
+// with RunSweep instead.
+func (d *Device) RunSweepBinary() (SweepData, error) {
+	return d.RunSweepBinaryContext(context.Background())
+}
+
+// RunSweepBinaryContext is like RunSweepBinary but stops waiting for the
+// device once ctx is done.
+func (d *Device) RunSweepBinaryContext(ctx context.Context) (SweepData, error) {
+	if !d.hardwareInfo.Capabilities.HasBinaryScan {
+		return d.RunSweepContext(ctx)
+	}
+	cfg, ok := d.GetSweepConfig()
+	if !ok {
+		return SweepData{}, errors.New("no sweep configured; call SetSweepConfig first")
+	}
+	if err := ctx.Err(); err != nil {
+		return SweepData{}, err
+	}
+
+	timeout := binaryScanTimeout
+	if deadline, has := ctx.Deadline(); has && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	cmd := fmt.Sprintf("scan_bin %d %d %d %d", cfg.StartHz, cfg.StopHz, cfg.Points, scanBinMask)
+	prompt := d.prompt()
+	resp, err := d.Transaction([]byte(cmd+d.commandTerminator()), func(b []byte) bool {
+		frame, ok := commandPayload(b, cmd)
+		if !ok {
+			return strings.Contains(string(b), prompt) // unexpected text reply
+		}
+		if scanBinRejected(frame) {
+			return true
+		}
+		return len(frame) >= scanBinHeaderSize+cfg.Points*scanBinRecordSize &&
+			strings.Contains(string(frame[scanBinHeaderSize+cfg.Points*scanBinRecordSize:]), prompt)
+	}, timeout)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return SweepData{}, ctxErr
+	}
+	if frame, ok := commandPayload(resp, cmd); ok && scanBinRejected(frame) {
+		return d.RunSweepContext(ctx)
+	}
+	if err != nil {
+		return SweepData{}, fmt.Errorf("binary scan failed: %v", err)
+	}
+
+	frame, _ := commandPayload(resp, cmd)
+	data, err := decodeScanBin(frame, d.GetByteOrder())
+	if err != nil {
+		return SweepData{}, err
+	}
+	if len(data.Frequencies) != cfg.Points {
+		return SweepData{}, fmt.Errorf("binary scan returned %d points, want %d", len(data.Frequencies), cfg.Points)
+	}
+	data.Z0 = d.portZ0
+	data = d.checkSweepOrder(data)
+	if d.sortByFrequency {
+		data = data.SortByFrequency()
+	}
+	return data, nil
+}
+
+// commandPayload returns the bytes of a binary reply following the echoed
+// command line, and false while the echo line is incomplete.
+func commandPayload(resp []byte, cmd string) ([]byte, bool) {
+	s := string(resp)
+	i := strings.Index(s, cmd)
+	if i < 0 {
+		return nil, false
+	}
+	nl := strings.IndexByte(s[i:], '\n')
+	if nl < 0 {
+		return nil, false
+	}
+	return resp[i+nl+1:], true
+}
+
+// scanBinRejected reports whether the payload is the shell's unknown-command
+// reply. A binary header never starts with a printable character, since the
+// low byte of the mask has the binary bit set.
+func scanBinRejected(payload []byte) bool {
+	return strings.HasPrefix(string(payload), "?") || strings.HasPrefix(string(payload), "scan_bin?")
+}
+
+// decodeScanBin decodes a scan_bin header and its records. Any bytes after
+// the records, such as the prompt, are ignored.
+func decodeScanBin(frame []byte, order binary.ByteOrder) (SweepData, error) {
+	if len(frame) < scanBinHeaderSize {
+		return SweepData{}, errors.New("binary scan frame too short for header")
+	}
+	mask := order.Uint16(frame[0:2])
+	points := int(order.Uint16(frame[2:4]))
+	if mask&^scanBinBinary != scanBinMask&^scanBinBinary {
+		return SweepData{}, fmt.Errorf("unexpected binary scan mask 0x%02x", mask)
+	}
+	if need := scanBinHeaderSize + points*scanBinRecordSize; len(frame) < need {
+		return SweepData{}, fmt.Errorf("binary scan frame has %d bytes, want %d", len(frame), need)
+	}
+
+	f32 := func(b []byte) float64 { return float64(math.Float32frombits(order.Uint32(b))) }
+	data := SweepData{
+		Frequencies: make([]float64, points),
+		S11:         make([]complex128, points),
+		S21:         make([]complex128, points),
+	}
+	for i := 0; i < points; i++ {
+		r := frame[scanBinHeaderSize+i*scanBinRecordSize:]
+		data.Frequencies[i] = float64(order.Uint32(r[0:4]))
+		data.S11[i] = complex(f32(r[4:8]), f32(r[8:12]))
+		data.S21[i] = complex(f32(r[12:16]), f32(r[16:20]))
+	}
+	return data, nil
+}
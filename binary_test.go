@@ -0,0 +1,74 @@
+package nanovna
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestDevice_ByteOrder(t *testing.T) {
+	dev := &Device{}
+	if dev.GetByteOrder() != binary.LittleEndian {
+		t.Error("default byte order should be little-endian")
+	}
+	dev.SetByteOrder(binary.BigEndian)
+	if dev.GetByteOrder() != binary.BigEndian {
+		t.Error("SetByteOrder did not take effect")
+	}
+	dev.SetByteOrder(nil)
+	if dev.GetByteOrder() != binary.LittleEndian {
+		t.Error("SetByteOrder(nil) should restore the default")
+	}
+}
+
+// scanBinFrame builds a scan_bin reply body for the given points.
+func scanBinFrame(freqs []uint32, s11, s21 []complex64) []byte {
+	le := binary.LittleEndian
+	b := le.AppendUint16(nil, scanBinMask)
+	b = le.AppendUint16(b, uint16(len(freqs)))
+	for i, f := range freqs {
+		b = le.AppendUint32(b, f)
+		for _, v := range []complex64{s11[i], s21[i]} {
+			b = le.AppendUint32(b, math.Float32bits(real(v)))
+			b = le.AppendUint32(b, math.Float32bits(imag(v)))
+		}
+	}
+	return b
+}
+
+func TestDevice_RunSweepBinary(t *testing.T) {
+	cmd := "scan_bin 1000000 2000000 2 135"
+	frame := scanBinFrame([]uint32{1000000, 2000000},
+		[]complex64{complex(0.5, -0.25), complex(0.1, 0.2)},
+		[]complex64{complex(0.9, 0), complex(0.8, -0.1)})
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 2000000 2": shellResponse("sweep 1000000 2000000 2"),
+		cmd:                       cmd + "\r\n" + string(frame) + "2> ",
+	}}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantV2
+	dev.hardwareInfo = getHardwareInfo(VariantV2)
+	if err := dev.SetSweepConfig(1000000, 2000000, 2); err != nil {
+		t.Fatalf("SetSweepConfig failed: %v", err)
+	}
+
+	data, err := dev.RunSweepBinary()
+	if err != nil {
+		t.Fatalf("RunSweepBinary failed: %v", err)
+	}
+	if len(data.Frequencies) != 2 || data.Frequencies[1] != 2e6 {
+		t.Fatalf("Frequencies = %v", data.Frequencies)
+	}
+	if data.S11[0] != complex(0.5, -0.25) || data.S21[1] != complex(float64(float32(0.8)), float64(float32(-0.1))) {
+		t.Errorf("decoded S11 = %v, S21 = %v", data.S11, data.S21)
+	}
+
+	// Firmware without scan_bin falls back to the text protocol
+	port.Responses[cmd] = shellResponse(cmd, "scan_bin?")
+	port.Responses["freq"] = shellResponse("freq", "1000000", "2000000")
+	port.Responses["data 0"] = shellResponse("data 0", "0.1 0", "0.2 0")
+	data, err = dev.RunSweepBinary()
+	if err != nil || len(data.S11) != 2 || data.S11[1] != 0.2 {
+		t.Errorf("fallback sweep = %+v, %v", data, err)
+	}
+}
@@ -0,0 +1,224 @@
+package nanovna
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ApplyCalibration applies software-side error correction to a raw sweep.
+// S11 receives one-port correction per point,
+//
+//	S11a = (S11m − e00) / (e11·(S11m − e00) + e10e01)
+//
+// and, when cal has through terms and raw has S21, S21 receives enhanced
+// response correction,
+//
+//	S21a = (S21m − e30) · (1 − e11·S11a) / e10e32
+//
+// with e30 taken as zero when cal has no isolation term. The calibration
+// frequency grid must match raw.Frequencies to within 1 Hz per point; use a
+// calibration measured on the same sweep configuration. S12 and S22 are
+// copied through uncorrected, since the error model covers the forward
+// direction only.
+func (d *Device) ApplyCalibration(raw SweepData, cal CalibrationData) (SweepData, error) {
+	if !cal.Valid() {
+		return SweepData{}, errors.New("calibration terms do not match its frequency grid")
+	}
+	if len(raw.S11) != len(raw.Frequencies) {
+		return SweepData{}, errors.New("frequency and S11 lengths differ")
+	}
+	if !sameGrid(raw.Frequencies, cal.Frequencies, gridToleranceHz) {
+		return SweepData{}, fmt.Errorf("calibration grid (%d points) does not match sweep grid (%d points)",
+			len(cal.Frequencies), len(raw.Frequencies))
+	}
+
+	out := SweepData{
+		Frequencies: append([]float64(nil), raw.Frequencies...),
+		S11:         make([]complex128, len(raw.S11)),
+		S21:         append([]complex128(nil), raw.S21...),
+		S12:         append([]complex128(nil), raw.S12...),
+		S22:         append([]complex128(nil), raw.S22...),
+		Z0:          raw.Z0,
+	}
+	for i, m := range raw.S11 {
+		md := m - cal.Directivity[i]
+		out.S11[i] = md / (cal.SourceMatch[i]*md + cal.ReflectionTracking[i])
+	}
+
+	if cal.HasThrough() && len(raw.S21) == len(raw.S11) {
+		for i, m := range raw.S21 {
+			if len(cal.Isolation) == len(cal.Frequencies) {
+				m -= cal.Isolation[i]
+			}
+			out.S21[i] = m * (1 - cal.SourceMatch[i]*out.S11[i]) / cal.TransmissionTracking[i]
+		}
+	}
+	return out, nil
+}
+
+// CalStandard identifies a calibration standard.
+type CalStandard int
+
+const (
+	CalShort CalStandard = iota // Short, Γ = −1
+	CalOpen                     // Open, Γ = +1
+	CalLoad                     // Matched load, Γ = 0
+	CalThru                     // Thru between port 1 and port 2, S21 = 1
+)
+
+// String returns the string representation of the standard.
+func (cs CalStandard) String() string {
+	switch cs {
+	case CalShort:
+		return "Short"
+	case CalOpen:
+		return "Open"
+	case CalLoad:
+		return "Load"
+	case CalThru:
+		return "Thru"
+	default:
+		return "Unknown"
+	}
+}
+
+// CalSession is a guided Short-Open-Load-Thru calibration started with
+// StartCalibration. Measure each standard once, then call Finish to solve
+// for the error terms. The standards are assumed ideal.
+type CalSession struct {
+	dev   *Device
+	grid  []float64
+	ports int
+	raw   map[CalStandard]SweepData
+}
+
+// StartCalibration begins a calibration on freqGrid, which must be an
+// ascending, uniformly spaced grid; the sweep is configured from its first
+// and last frequency and its length. The session is one-port (Short, Open,
+// Load) unless SetPortCount(2) adds the Thru.
+func (d *Device) StartCalibration(freqGrid []float64) (*CalSession, error) {
+	n := len(freqGrid)
+	if n < 2 {
+		return nil, fmt.Errorf("calibration grid needs at least 2 points, got %d", n)
+	}
+	step := (freqGrid[n-1] - freqGrid[0]) / float64(n-1)
+	for i, f := range freqGrid {
+		if step <= 0 || math.Abs(f-(freqGrid[0]+float64(i)*step)) > gridToleranceHz {
+			return nil, errors.New("calibration grid must be ascending and uniformly spaced")
+		}
+	}
+	if err := d.SetSweepConfig(int(math.Round(freqGrid[0])), int(math.Round(freqGrid[n-1])), n); err != nil {
+		return nil, err
+	}
+	return &CalSession{
+		dev:   d,
+		grid:  append([]float64(nil), freqGrid...),
+		ports: 1,
+		raw:   make(map[CalStandard]SweepData),
+	}, nil
+}
+
+// SetPortCount selects a one-port (1) or two-port (2) calibration. A
+// two-port calibration additionally requires the Thru standard.
+func (s *CalSession) SetPortCount(n int) error {
+	if n != 1 && n != 2 {
+		return fmt.Errorf("port count must be 1 or 2, got %d", n)
+	}
+	s.ports = n
+	return nil
+}
+
+// MeasureShort measures the Short standard on port 1.
+func (s *CalSession) MeasureShort() error { return s.measure(CalShort) }
+
+// MeasureOpen measures the Open standard on port 1.
+func (s *CalSession) MeasureOpen() error { return s.measure(CalOpen) }
+
+// MeasureLoad measures the Load standard on port 1. In a two-port session
+// its S21 is also used as the isolation term, so port 2 should be
+// terminated too.
+func (s *CalSession) MeasureLoad() error { return s.measure(CalLoad) }
+
+// MeasureThru measures the Thru standard connecting port 1 to port 2.
+func (s *CalSession) MeasureThru() error { return s.measure(CalThru) }
+
+// Discard forgets the measurement of std so that it can be measured again.
+func (s *CalSession) Discard(std CalStandard) {
+	delete(s.raw, std)
+}
+
+// measure runs a sweep for std and stores it. Measuring a standard twice is
+// an error unless it was discarded in between.
+func (s *CalSession) measure(std CalStandard) error {
+	if _, done := s.raw[std]; done {
+		return fmt.Errorf("%s already measured; call Discard to measure it again", std)
+	}
+	data, err := s.dev.RunSweep()
+	if err != nil {
+		return fmt.Errorf("%s: %v", std, err)
+	}
+	if !sameGrid(data.Frequencies, s.grid, gridToleranceHz) || len(data.S11) != len(s.grid) {
+		return fmt.Errorf("%s: sweep does not match the %d-point calibration grid", std, len(s.grid))
+	}
+	if std == CalThru && len(data.S21) != len(s.grid) {
+		return fmt.Errorf("%s: sweep has no S21 data", std)
+	}
+	s.raw[std] = data
+	return nil
+}
+
+// Finish solves for the error terms from the measured standards. One-port
+// sessions need Short, Open and Load; two-port sessions also need Thru.
+// The result can be passed to ApplyCalibration.
+func (s *CalSession) Finish() (CalibrationData, error) {
+	required := []CalStandard{CalShort, CalOpen, CalLoad}
+	if s.ports == 2 {
+		required = append(required, CalThru)
+	}
+	for _, std := range required {
+		if _, ok := s.raw[std]; !ok {
+			return CalibrationData{}, fmt.Errorf("%s standard not measured", std)
+		}
+	}
+
+	n := len(s.grid)
+	cal := CalibrationData{
+		Frequencies:        append([]float64(nil), s.grid...),
+		Standards:          required,
+		Directivity:        make([]complex128, n),
+		SourceMatch:        make([]complex128, n),
+		ReflectionTracking: make([]complex128, n),
+	}
+	short, open, load := s.raw[CalShort].S11, s.raw[CalOpen].S11, s.raw[CalLoad].S11
+	for i := 0; i < n; i++ {
+		// With ideal standards: load gives e00 directly, and open (Γ=+1)
+		// and short (Γ=−1) give two equations in e11 and e10e01
+		e00 := load[i]
+		a, b := open[i]-e00, short[i]-e00
+		if a == b {
+			return CalibrationData{}, fmt.Errorf("open and short are identical at %.0f Hz", s.grid[i])
+		}
+		cal.Directivity[i] = e00
+		cal.SourceMatch[i] = (a + b) / (a - b)
+		cal.ReflectionTracking[i] = -2 * a * b / (a - b)
+	}
+
+	if s.ports == 2 {
+		thru := s.raw[CalThru]
+		cal.TransmissionTracking = make([]complex128, n)
+		if loadS21 := s.raw[CalLoad].S21; len(loadS21) == n {
+			cal.Isolation = append([]complex128(nil), loadS21...)
+		}
+		for i := 0; i < n; i++ {
+			md := thru.S11[i] - cal.Directivity[i]
+			s11a := md / (cal.SourceMatch[i]*md + cal.ReflectionTracking[i])
+			m := thru.S21[i]
+			if cal.Isolation != nil {
+				m -= cal.Isolation[i]
+			}
+			cal.TransmissionTracking[i] = m * (1 - cal.SourceMatch[i]*s11a)
+		}
+	}
+	return cal, nil
+}
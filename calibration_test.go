@@ -0,0 +1,135 @@
+package nanovna
+
+import (
+	"fmt"
+	"math/cmplx"
+	"testing"
+)
+
+func TestDevice_ApplyCalibration(t *testing.T) {
+	cal := CalibrationData{
+		Frequencies:          []float64{1e6},
+		Directivity:          []complex128{0.05},
+		SourceMatch:          []complex128{complex(0.1, 0.05)},
+		ReflectionTracking:   []complex128{complex(0.9, -0.1)},
+		TransmissionTracking: []complex128{0.8},
+		Isolation:            []complex128{0.001},
+	}
+	// Forward model: S11m = e00 + e10e01·Γ/(1 − e11·Γ)
+	gamma := complex(0.3, -0.2)
+	s11m := cal.Directivity[0] + cal.ReflectionTracking[0]*gamma/(1-cal.SourceMatch[0]*gamma)
+	s21 := complex(0.5, 0.1)
+	s21m := cal.Isolation[0] + s21*cal.TransmissionTracking[0]/(1-cal.SourceMatch[0]*gamma)
+
+	raw := SweepData{Frequencies: []float64{1e6}, S11: []complex128{s11m}, S21: []complex128{s21m},
+		S22: []complex128{0.2i}}
+	dev := &Device{}
+	got, err := dev.ApplyCalibration(raw, cal)
+	if err != nil {
+		t.Fatalf("ApplyCalibration failed: %v", err)
+	}
+	if cmplx.Abs(got.S11[0]-gamma) > 1e-12 {
+		t.Errorf("S11 = %v, want %v", got.S11[0], gamma)
+	}
+	if cmplx.Abs(got.S21[0]-s21) > 1e-12 {
+		t.Errorf("S21 = %v, want %v", got.S21[0], s21)
+	}
+	if len(got.S22) != 1 || got.S22[0] != 0.2i || got.S12 != nil {
+		t.Errorf("S12/S22 should pass through unchanged, got %v/%v", got.S12, got.S22)
+	}
+
+	raw.Frequencies = []float64{2e6}
+	if _, err := dev.ApplyCalibration(raw, cal); err == nil {
+		t.Error("Expected error for mismatched frequency grid")
+	}
+}
+
+func TestCalSession_SOLT(t *testing.T) {
+	e00, e11, e10e01 := complex(0.05, 0.01), complex(0.1, -0.05), complex(0.9, 0.1)
+	et, ex := complex(0.8, -0.2), complex(0.001, 0)
+	measured := func(gamma complex128) string {
+		m := e00 + e10e01*gamma/(1-e11*gamma)
+		return fmt.Sprintf("%g %g", real(m), imag(m))
+	}
+
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 2000000 2": shellResponse("sweep 1000000 2000000 2"),
+		"frequencies":             shellResponse("frequencies", "1000000", "2000000"),
+	}}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantV1
+	dev.hardwareInfo = getHardwareInfo(VariantV1)
+	set := func(s11 string, s21 complex128) {
+		port.Responses["data 0"] = shellResponse("data 0", s11, s11)
+		v := fmt.Sprintf("%g %g", real(s21), imag(s21))
+		port.Responses["data 1"] = shellResponse("data 1", v, v)
+	}
+
+	if _, err := dev.StartCalibration([]float64{1e6, 1.5e6, 3e6}); err == nil {
+		t.Error("Expected error for non-uniform grid")
+	}
+	session, err := dev.StartCalibration([]float64{1e6, 2e6})
+	if err != nil {
+		t.Fatalf("StartCalibration failed: %v", err)
+	}
+	if err := session.SetPortCount(2); err != nil {
+		t.Fatalf("SetPortCount failed: %v", err)
+	}
+
+	set(measured(-1), 0)
+	if err := session.MeasureShort(); err != nil {
+		t.Fatalf("MeasureShort failed: %v", err)
+	}
+	if err := session.MeasureShort(); err == nil {
+		t.Error("Expected error when measuring Short twice")
+	}
+	session.Discard(CalShort)
+	if err := session.MeasureShort(); err != nil {
+		t.Fatalf("MeasureShort after Discard failed: %v", err)
+	}
+	set(measured(1), 0)
+	if err := session.MeasureOpen(); err != nil {
+		t.Fatalf("MeasureOpen failed: %v", err)
+	}
+	set(measured(0), ex)
+	if err := session.MeasureLoad(); err != nil {
+		t.Fatalf("MeasureLoad failed: %v", err)
+	}
+	if _, err := session.Finish(); err == nil {
+		t.Error("Expected error for two-port Finish without Thru")
+	}
+
+	// Thru with a matched port 2: S11a = 0, S21m = EX + ET
+	set(measured(0), ex+et)
+	if err := session.MeasureThru(); err != nil {
+		t.Fatalf("MeasureThru failed: %v", err)
+	}
+	cal, err := session.Finish()
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want complex128
+	}{
+		{"Directivity", cal.Directivity[1], e00},
+		{"SourceMatch", cal.SourceMatch[1], e11},
+		{"ReflectionTracking", cal.ReflectionTracking[1], e10e01},
+		{"TransmissionTracking", cal.TransmissionTracking[1], et},
+		{"Isolation", cal.Isolation[1], ex},
+	} {
+		if cmplx.Abs(c.got-c.want) > 1e-5 {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if len(cal.Standards) != 4 || cal.Standards[3] != CalThru {
+		t.Errorf("Standards = %v, want Short, Open, Load, Thru", cal.Standards)
+	}
+	if !cal.Valid() || !cal.HasThrough() {
+		t.Error("solved calibration should be valid with through terms")
+	}
+
+	if err := session.SetPortCount(3); err == nil {
+		t.Error("Expected error for port count 3")
+	}
+}
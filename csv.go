@@ -0,0 +1,94 @@
+package nanovna
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// CSVOptions selects the columns written by WriteCSV. The frequency column
+// is always written first. The S-parameter columns are written for S11 and,
+// when the sweep has S21, repeated for S21; VSWR and return loss are
+// derived from S11 only.
+type CSVOptions struct {
+	FrequencyMHz bool // Frequency in MHz instead of Hz
+	RealImag     bool // Real and imaginary parts
+	MagnitudeDB  bool // Magnitude in dB
+	PhaseDegrees bool // Phase in degrees
+	VSWR         bool // S11 VSWR
+	ReturnLoss   bool // S11 return loss in dB
+}
+
+// WriteCSV writes the sweep as CSV with a header row and one row per
+// frequency point, with the columns chosen by opts.
+func (d SweepData) WriteCSV(w io.Writer, opts CSVOptions) error {
+	if len(d.S11) != len(d.Frequencies) {
+		return errors.New("frequency and S11 lengths differ")
+	}
+	params := []string{"s11"}
+	if len(d.S21) == len(d.S11) && len(d.S21) > 0 {
+		params = append(params, "s21")
+	}
+
+	header := []string{"freq_hz"}
+	if opts.FrequencyMHz {
+		header[0] = "freq_mhz"
+	}
+	for _, p := range params {
+		if opts.RealImag {
+			header = append(header, p+"_re", p+"_im")
+		}
+		if opts.MagnitudeDB {
+			header = append(header, p+"_db")
+		}
+		if opts.PhaseDegrees {
+			header = append(header, p+"_phase_deg")
+		}
+	}
+	if opts.VSWR {
+		header = append(header, "vswr")
+	}
+	if opts.ReturnLoss {
+		header = append(header, "return_loss_db")
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	row := make([]string, 0, len(header))
+	for i, f := range d.Frequencies {
+		if opts.FrequencyMHz {
+			f /= 1e6
+		}
+		row = append(row[:0], format(f))
+		for _, p := range params {
+			v := d.S11[i]
+			if p == "s21" {
+				v = d.S21[i]
+			}
+			if opts.RealImag {
+				row = append(row, format(real(v)), format(imag(v)))
+			}
+			if opts.MagnitudeDB {
+				row = append(row, format(magnitudeDB(v)))
+			}
+			if opts.PhaseDegrees {
+				row = append(row, format(phaseDegrees(v)))
+			}
+		}
+		if opts.VSWR {
+			row = append(row, format(reflectionVSWR(d.S11[i])))
+		}
+		if opts.ReturnLoss {
+			row = append(row, format(returnLossDB(d.S11[i])))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,44 @@
+package nanovna
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSweepData_WriteCSV(t *testing.T) {
+	data := SweepData{
+		Frequencies: []float64{1e6, 2e6},
+		S11:         []complex128{0, -0.5},
+	}
+	var buf bytes.Buffer
+	err := data.WriteCSV(&buf, CSVOptions{FrequencyMHz: true, RealImag: true, MagnitudeDB: true, VSWR: true})
+	if err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	if lines[0] != "freq_mhz,s11_re,s11_im,s11_db,vswr" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "2,-0.5,0,") || !strings.HasSuffix(lines[2], ",3") {
+		t.Errorf("row = %q, want frequency 2 MHz and VSWR 3", lines[2])
+	}
+
+	// S21 columns follow S11 when present
+	data.S21 = []complex128{1, 1}
+	buf.Reset()
+	if err := data.WriteCSV(&buf, CSVOptions{PhaseDegrees: true}); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "freq_hz,s11_phase_deg,s21_phase_deg\n") {
+		t.Errorf("unexpected header:\n%s", buf.String())
+	}
+
+	data.S11 = data.S11[:1]
+	if err := data.WriteCSV(&buf, CSVOptions{}); err == nil {
+		t.Error("Expected error for mismatched lengths")
+	}
+}
@@ -0,0 +1,93 @@
+package nanovna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DwellMode reports how a per-point settling delay is enforced.
+type DwellMode int
+
+const (
+	DwellNone     DwellMode = iota // No dwell time set
+	DwellFirmware                  // The firmware waits at each point
+	DwellHost                      // The host measures one point at a time
+)
+
+// String returns the string representation of the dwell mode.
+func (dm DwellMode) String() string {
+	switch dm {
+	case DwellNone:
+		return "none"
+	case DwellFirmware:
+		return "firmware"
+	case DwellHost:
+		return "host"
+	default:
+		return "unknown"
+	}
+}
+
+// SetDwellTime sets a settling delay at each sweep point, for high-Q DUTs
+// whose response a fast sweep would smear. Firmware with a `dwell` command
+// (taking microseconds) enforces it directly. Otherwise RunSweep falls back
+// to a host-side slow sweep that configures a single-point sweep at each
+// frequency of the current configuration, waits t, and reads that point;
+// this is much slower but works on any device. A zero or negative t
+// disables the dwell. DwellMode reports which mode is in effect.
+func (d *Device) SetDwellTime(t time.Duration) error {
+	return d.SetDwellTimeContext(context.Background(), t)
+}
+
+// SetDwellTimeContext is like SetDwellTime but stops waiting for the device
+// once ctx is done.
+func (d *Device) SetDwellTimeContext(ctx context.Context, t time.Duration) error {
+	if t <= 0 {
+		if d.dwellMode == DwellFirmware {
+			if _, err := d.readLinesContext(ctx, "dwell 0"); err != nil {
+				return err
+			}
+		}
+		d.dwellTime, d.dwellMode = 0, DwellNone
+		return nil
+	}
+
+	_, err := d.readLinesContext(ctx, fmt.Sprintf("dwell %d", t.Microseconds()))
+	switch {
+	case err == nil:
+		d.dwellMode = DwellFirmware
+	case errors.Is(err, ErrUnsupported):
+		d.dwellMode = DwellHost
+	default:
+		return err
+	}
+	d.dwellTime = t
+	return nil
+}
+
+// DwellMode returns how the dwell time set with SetDwellTime is enforced.
+func (d *Device) DwellMode() DwellMode {
+	return d.dwellMode
+}
+
+// runPointSweep measures the current sweep configuration one point at a
+// time, for a host-side dwell or a sweep mode the firmware cannot do
+// itself. With a host-side dwell it waits the dwell time before reading each
+// point. The original configuration is restored on the device afterwards.
+// Each finished point is reported to progress when it is non-nil.
+func (d *Device) runPointSweep(ctx context.Context, progress *sweepProgress) (SweepData, error) {
+	cfg, ok := d.GetSweepConfig()
+	if !ok {
+		return SweepData{}, errors.New("no sweep configured; call SetSweepConfig first")
+	}
+	// Leave the device sweeping the full configuration again
+	defer d.restoreSweepConfig(cfg)
+
+	var dwell time.Duration
+	if d.dwellMode == DwellHost {
+		dwell = d.dwellTime
+	}
+	return d.measurePoints(ctx, cfg.Frequencies(), dwell, progress)
+}
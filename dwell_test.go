@@ -0,0 +1,42 @@
+package nanovna
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDevice_SetDwellTime_HostFallback(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"dwell 1000":              shellResponse("dwell 1000", "dwell?"),
+		"sweep 1000000 2000000 2": shellResponse("sweep 1000000 2000000 2"),
+		"sweep 1000000 1000000 1": shellResponse("sweep 1000000 1000000 1"),
+		"sweep 2000000 2000000 1": shellResponse("sweep 2000000 2000000 1"),
+		"frequencies":             shellResponse("frequencies", "1500000"),
+		"data 0":                  shellResponse("data 0", "0.1 0.2"),
+	}}
+	dev, _ := Open("COM1", port)
+	if err := dev.SetDwellTime(time.Millisecond); err != nil {
+		t.Fatalf("SetDwellTime failed: %v", err)
+	}
+	if dev.DwellMode() != DwellHost {
+		t.Fatalf("DwellMode() = %v, want host", dev.DwellMode())
+	}
+	if err := dev.SetSweepConfig(1000000, 2000000, 2); err != nil {
+		t.Fatalf("SetSweepConfig failed: %v", err)
+	}
+
+	data, err := dev.RunSweep()
+	if err != nil {
+		t.Fatalf("RunSweep failed: %v", err)
+	}
+	if len(data.S11) != 2 || len(data.Frequencies) != 2 {
+		t.Errorf("got %d points, want 2", len(data.S11))
+	}
+	if last := port.Written[len(port.Written)-1]; last != "sweep 1000000 2000000 2" {
+		t.Errorf("sweep configuration not restored, last command %q", last)
+	}
+
+	if err := dev.SetDwellTime(0); err != nil || dev.DwellMode() != DwellNone {
+		t.Errorf("SetDwellTime(0) = %v, mode %v", err, dev.DwellMode())
+	}
+}
@@ -0,0 +1,365 @@
+package nanovna
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rejectedCommand reports whether resp is the firmware shell's answer to an
+// unknown command: a line consisting of "?" or the command name followed by "?".
+func rejectedCommand(resp, cmd string) bool {
+	name := cmd
+	if fields := strings.Fields(cmd); len(fields) > 0 {
+		name = fields[0]
+	}
+	for _, line := range strings.Split(resp, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "?" || line == name+"?" {
+			return true
+		}
+	}
+	return false
+}
+
+// ThreadInfo describes one firmware task as reported by the `threads` command.
+type ThreadInfo struct {
+	Name      string
+	State     string
+	Priority  int    // -1 when not reported
+	StackFree int    // Free stack in bytes, -1 when not reported
+	Address   string // Thread control block address as printed by the firmware
+}
+
+// GetThreads returns the firmware task list from the ChibiOS `threads` shell
+// command, which is useful when investigating lockups. Columns are matched
+// by their header names, so fields missing from a firmware build are left at
+// their "not reported" values. Returns ErrUnsupported when the command is absent.
+func (d *Device) GetThreads() ([]ThreadInfo, error) {
+	return d.GetThreadsContext(context.Background())
+}
+
+// GetThreadsContext is like GetThreads but stops waiting for the device once
+// ctx is done.
+func (d *Device) GetThreadsContext(ctx context.Context) ([]ThreadInfo, error) {
+	lines, err := d.readLinesContext(ctx, "threads")
+	if err != nil {
+		return nil, err
+	}
+
+	var header []string
+	var threads []ThreadInfo
+	for _, line := range lines {
+		cols := strings.Split(line, "|")
+		for i := range cols {
+			cols[i] = strings.TrimSpace(cols[i])
+		}
+		if header == nil {
+			header = cols
+			continue
+		}
+
+		t := ThreadInfo{Priority: -1, StackFree: -1}
+		for i, col := range cols {
+			if i >= len(header) {
+				break
+			}
+			switch strings.ToLower(header[i]) {
+			case "name":
+				t.Name = col
+			case "state":
+				t.State = col
+			case "prio":
+				if v, err := strconv.Atoi(col); err == nil {
+					t.Priority = v
+				}
+			case "stk free", "free", "stack free":
+				if v, err := strconv.ParseInt(col, 16, 64); err == nil {
+					t.StackFree = int(v)
+				}
+			case "addr":
+				t.Address = col
+			}
+		}
+		threads = append(threads, t)
+	}
+
+	if header == nil {
+		return nil, fmt.Errorf("empty threads response")
+	}
+	return threads, nil
+}
+
+// GetPortZ0 reads the port reference impedance stored in the device, in ohms.
+// Returns ErrUnsupported when the firmware has no `portz` command.
+func (d *Device) GetPortZ0() (float64, error) {
+	return d.GetPortZ0Context(context.Background())
+}
+
+// GetPortZ0Context is like GetPortZ0 but stops waiting for the device once
+// ctx is done.
+func (d *Device) GetPortZ0Context(ctx context.Context) (float64, error) {
+	lines, err := d.readLinesContext(ctx, "portz")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		z0, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+		if err == nil && z0 > 0 {
+			d.portZ0 = z0
+			return z0, nil
+		}
+	}
+	return 0, fmt.Errorf("unexpected portz response: %q", lines)
+}
+
+// SetPortZ0 changes the port reference impedance the device itself uses, for
+// example 75 for cable-TV work. This is separate from the host-side Z0 passed
+// to the analysis helpers; subsequent sweeps carry the new value in
+// SweepData.Z0 so both stay consistent. Returns ErrUnsupported when the
+// firmware has no `portz` command.
+func (d *Device) SetPortZ0(z0 float64) error {
+	return d.SetPortZ0Context(context.Background(), z0)
+}
+
+// SetPortZ0Context is like SetPortZ0 but stops waiting for the device once
+// ctx is done.
+func (d *Device) SetPortZ0Context(ctx context.Context, z0 float64) error {
+	if z0 <= 0 {
+		return fmt.Errorf("port Z0 must be positive, got %g", z0)
+	}
+	if _, err := d.readLinesContext(ctx, fmt.Sprintf("portz %g", z0)); err != nil {
+		return err
+	}
+	d.portZ0 = z0
+	return nil
+}
+
+// deviceTimeLayout is the date format printed by the firmware `time` command.
+const deviceTimeLayout = "2006/01/02 15:04:05"
+
+// GetDeviceTime reads the real-time clock of devices fitted with one (such as
+// NanoVNA-H with the RTC modification) via the firmware `time` command. The
+// device clock carries no time zone; it is interpreted as UTC, matching
+// SetDeviceTime. Returns ErrUnsupported when the firmware reports no clock.
+func (d *Device) GetDeviceTime() (time.Time, error) {
+	return d.GetDeviceTimeContext(context.Background())
+}
+
+// GetDeviceTimeContext is like GetDeviceTime but stops waiting for the
+// device once ctx is done.
+func (d *Device) GetDeviceTimeContext(ctx context.Context) (time.Time, error) {
+	lines, err := d.readLinesContext(ctx, "time")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range lines {
+		if t, err := time.Parse(deviceTimeLayout, line); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrUnsupported
+}
+
+// SetDeviceTime sets the device real-time clock to t (converted to UTC) using
+// the firmware's `time b 0xYYMMDD 0xHHMMSS` form. The clock only stores
+// years 2000-2099. Returns ErrUnsupported when the firmware has no clock.
+func (d *Device) SetDeviceTime(t time.Time) error {
+	return d.SetDeviceTimeContext(context.Background(), t)
+}
+
+// SetDeviceTimeContext is like SetDeviceTime but stops waiting for the
+// device once ctx is done.
+func (d *Device) SetDeviceTimeContext(ctx context.Context, t time.Time) error {
+	t = t.UTC()
+	if t.Year() < 2000 || t.Year() > 2099 {
+		return fmt.Errorf("year %d outside device clock range 2000-2099", t.Year())
+	}
+	cmd := fmt.Sprintf("time b 0x%02d%02d%02d 0x%02d%02d%02d",
+		t.Year()%100, int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second())
+	_, err := d.readLinesContext(ctx, cmd)
+	return err
+}
+
+// Status register bits as reported by the `status` command.
+const (
+	statusPLLLocked  = 1 << 0
+	statusOverrange  = 1 << 1
+	statusCalibrated = 1 << 2
+	statusSweeping   = 1 << 3
+)
+
+// DeviceStatus is the decoded device status register.
+//
+// The register is only present on the V2 family (v2, v2 Plus, v2 Plus4,
+// SAA2) and LiteVNA:
+//
+//   - PLLLocked: the synthesizer PLL is locked; data taken while it is
+//     unlocked is at an unknown frequency and should be discarded
+//   - Overrange: the receiver ADC clipped during the last sweep
+//   - Calibrated: a calibration is loaded and being applied
+//   - Sweeping: a sweep is in progress
+//
+// NanoVNA v1, NanoVNA-H and TinySA firmware have no status register.
+type DeviceStatus struct {
+	PLLLocked  bool
+	Overrange  bool
+	Calibrated bool
+	Sweeping   bool
+	Raw        uint32 // Register value as read, including undocumented bits
+}
+
+// GetStatus reads and decodes the device status register, for example to
+// discard sweeps taken while the PLL was unlocked. Returns ErrUnsupported on
+// variants without a status register or when the firmware rejects the command.
+func (d *Device) GetStatus() (DeviceStatus, error) {
+	return d.GetStatusContext(context.Background())
+}
+
+// GetStatusContext is like GetStatus but stops waiting for the device once
+// ctx is done.
+func (d *Device) GetStatusContext(ctx context.Context) (DeviceStatus, error) {
+	switch d.variant {
+	case VariantV2, VariantV2Plus, VariantV2Plus4, VariantSAA2, VariantLiteVNA:
+	default:
+		return DeviceStatus{}, ErrUnsupported
+	}
+
+	lines, err := d.readLinesContext(ctx, "status")
+	if err != nil {
+		return DeviceStatus{}, err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		raw, err := strconv.ParseUint(fields[len(fields)-1], 0, 32)
+		if err != nil {
+			continue
+		}
+		return DeviceStatus{
+			PLLLocked:  raw&statusPLLLocked != 0,
+			Overrange:  raw&statusOverrange != 0,
+			Calibrated: raw&statusCalibrated != 0,
+			Sweeping:   raw&statusSweeping != 0,
+			Raw:        uint32(raw),
+		}, nil
+	}
+	return DeviceStatus{}, fmt.Errorf("unexpected status response: %q", lines)
+}
+
+// GetMemoryTrace would read a trace the user stored to memory on the device,
+// for side-by-side comparison with live sweeps, but no known firmware
+// exposes stored traces over the shell: `data` only serves channels 0-6.
+// It always returns ErrUnsupported without contacting the device; keep
+// reference traces on the host with WriteTouchstoneS1P instead.
+func (d *Device) GetMemoryTrace(slot int) (SweepData, error) {
+	return d.GetMemoryTraceContext(context.Background(), slot)
+}
+
+// GetMemoryTraceContext is like GetMemoryTrace; ctx is unused.
+func (d *Device) GetMemoryTraceContext(ctx context.Context, slot int) (SweepData, error) {
+	return SweepData{}, ErrUnsupported
+}
+
+// BatteryVoltage reads the battery voltage in millivolts via the firmware
+// `vbat` command (for example "4012 mV"), so operators running on battery
+// can check the charge before a long session. Returns ErrUnsupported on
+// variants without HasBattery or when the firmware rejects the command.
+func (d *Device) BatteryVoltage() (int, error) {
+	return d.BatteryVoltageContext(context.Background())
+}
+
+// BatteryVoltageContext is like BatteryVoltage but stops waiting for the
+// device once ctx is done.
+func (d *Device) BatteryVoltageContext(ctx context.Context) (int, error) {
+	if !d.hardwareInfo.Capabilities.HasBattery {
+		return 0, ErrUnsupported
+	}
+	lines, err := d.readLinesContext(ctx, "vbat")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		mv, err := strconv.Atoi(strings.TrimSuffix(fields[0], "mV"))
+		if err == nil && mv >= 0 {
+			return mv, nil
+		}
+	}
+	return 0, fmt.Errorf("unexpected vbat response: %q", lines)
+}
+
+// BandwidthsHz lists the IF bandwidths SetBandwidth accepts, narrowest
+// first. Each step down lowers the noise floor at the cost of sweep time.
+var BandwidthsHz = []int{10, 30, 100, 300, 1000, 2000}
+
+// SetBandwidth sets the receiver IF bandwidth in Hz with the firmware
+// `bandwidth` command; hz must be one of BandwidthsHz. Narrow bandwidths
+// trade sweep speed for dynamic range, for example when measuring
+// high-rejection filters. Returns ErrUnsupported on variants without
+// HasBandwidthControl or when the firmware rejects the command.
+func (d *Device) SetBandwidth(hz int) error {
+	return d.SetBandwidthContext(context.Background(), hz)
+}
+
+// SetBandwidthContext is like SetBandwidth but stops waiting for the device
+// once ctx is done.
+func (d *Device) SetBandwidthContext(ctx context.Context, hz int) error {
+	valid := false
+	for _, bw := range BandwidthsHz {
+		valid = valid || bw == hz
+	}
+	if !valid {
+		return fmt.Errorf("bandwidth %d Hz not one of %v", hz, BandwidthsHz)
+	}
+	if !d.hardwareInfo.Capabilities.HasBandwidthControl {
+		return ErrUnsupported
+	}
+	_, err := d.readLinesContext(ctx, fmt.Sprintf("bandwidth %d", hz))
+	return err
+}
+
+// firmwareVersionPattern matches the dotted version number in `version`
+// output such as "1.2.27", "0.9.3-4-g1b2c3d4" or "v1.0.45".
+var firmwareVersionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// FirmwareVersion runs CommandSet.VersionCommand and parses the first dotted
+// number in its output into major, minor and patch (patch is 0 when the
+// firmware prints only two components). raw is the payload as printed, and
+// is returned alongside an error when it holds no version number, as with
+// date-stamped V2 builds.
+func (d *Device) FirmwareVersion() (major, minor, patch int, raw string, err error) {
+	return d.FirmwareVersionContext(context.Background())
+}
+
+// FirmwareVersionContext is like FirmwareVersion but stops waiting for the
+// device once ctx is done.
+func (d *Device) FirmwareVersionContext(ctx context.Context) (major, minor, patch int, raw string, err error) {
+	lines, err := d.readLinesContext(ctx, d.hardwareInfo.CommandSet.VersionCommand)
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+	raw = strings.Join(lines, "\n")
+	m := firmwareVersionPattern.FindStringSubmatch(raw)
+	if m == nil {
+		return 0, 0, 0, raw, fmt.Errorf("no version number in %q", raw)
+	}
+	major, _ = strconv.Atoi(m[1])
+	minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		patch, _ = strconv.Atoi(m[3])
+	}
+	return major, minor, patch, raw, nil
+}
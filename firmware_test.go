@@ -0,0 +1,177 @@
+package nanovna
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDevice_GetThreads(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"threads": shellResponse("threads",
+			"stklimit|   stack|stk free|    addr|refs|prio|    state|         name",
+			"20000200|2000054C|00000178|200016A8|   0| 128|  CURRENT|         main",
+			"20001A00|20001B3C|000000C4|20001C40|   0|   1|    READY|         idle"),
+	}}
+	dev, _ := Open("COM1", port)
+	threads, err := dev.GetThreads()
+	if err != nil {
+		t.Fatalf("GetThreads failed: %v", err)
+	}
+	if len(threads) != 2 {
+		t.Fatalf("got %d threads, want 2", len(threads))
+	}
+	main := threads[0]
+	if main.Name != "main" || main.State != "CURRENT" || main.Priority != 128 || main.StackFree != 0x178 {
+		t.Errorf("unexpected thread: %+v", main)
+	}
+}
+
+func TestDevice_GetThreads_Unsupported(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"threads": shellResponse("threads", "threads?"),
+	}}
+	dev, _ := Open("COM1", port)
+	if _, err := dev.GetThreads(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestDevice_PortZ0(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"portz":    shellResponse("portz", "50"),
+		"portz 75": shellResponse("portz 75"),
+	}}
+	dev, _ := Open("COM1", port)
+	z0, err := dev.GetPortZ0()
+	if err != nil || z0 != 50 {
+		t.Fatalf("GetPortZ0() = %v, %v; want 50", z0, err)
+	}
+	if err := dev.SetPortZ0(75); err != nil {
+		t.Fatalf("SetPortZ0 failed: %v", err)
+	}
+	if dev.portZ0 != 75 {
+		t.Errorf("cached port Z0 = %v, want 75", dev.portZ0)
+	}
+	if err := dev.SetPortZ0(0); err == nil {
+		t.Error("Expected error for zero Z0")
+	}
+}
+
+func TestDevice_DeviceTime(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"time": shellResponse("time", "2024/03/05 07:08:09",
+			"usage: time {[y|m|d|h|min|sec|ppm] 0-99} or {b 0xYYMMDD 0xHHMMSS}"),
+		"time b 0x240305 0x070809": shellResponse("time b 0x240305 0x070809"),
+	}}
+	dev, _ := Open("COM1", port)
+	got, err := dev.GetDeviceTime()
+	want := time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC)
+	if err != nil || !got.Equal(want) {
+		t.Fatalf("GetDeviceTime() = %v, %v; want %v", got, err, want)
+	}
+	if err := dev.SetDeviceTime(want); err != nil {
+		t.Fatalf("SetDeviceTime failed: %v", err)
+	}
+	if last := port.Written[len(port.Written)-1]; last != "time b 0x240305 0x070809" {
+		t.Errorf("SetDeviceTime wrote %q", last)
+	}
+}
+
+func TestDevice_GetStatus(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"status": shellResponse("status", "0x05"),
+	}}
+	dev, _ := Open("COM1", port)
+	if _, err := dev.GetStatus(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for unknown variant, got %v", err)
+	}
+
+	dev.variant = VariantV2
+	dev.hardwareInfo = getHardwareInfo(VariantV2)
+	st, err := dev.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	want := DeviceStatus{PLLLocked: true, Calibrated: true, Raw: 5}
+	if st != want {
+		t.Errorf("GetStatus() = %+v, want %+v", st, want)
+	}
+}
+
+func TestDevice_GetMemoryTrace(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{}}
+	dev, _ := Open("COM1", port)
+	for _, slot := range []int{0, 1} {
+		if _, err := dev.GetMemoryTrace(slot); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("GetMemoryTrace(%d): expected ErrUnsupported, got %v", slot, err)
+		}
+	}
+	if len(port.Written) != 0 {
+		t.Errorf("GetMemoryTrace sent %q", port.Written)
+	}
+}
+
+func TestDevice_BatteryVoltage(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"vbat": shellResponse("vbat", "4012 mV"),
+	}}
+	dev, _ := Open("COM1", port)
+	dev.hardwareInfo = getHardwareInfo(VariantVH)
+	mv, err := dev.BatteryVoltage()
+	if err != nil || mv != 4012 {
+		t.Fatalf("BatteryVoltage() = %v, %v; want 4012", mv, err)
+	}
+
+	dev.hardwareInfo = getHardwareInfo(VariantV2)
+	if _, err := dev.BatteryVoltage(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported on V2, got %v", err)
+	}
+}
+
+func TestDevice_SetBandwidth(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"bandwidth 100": shellResponse("bandwidth 100"),
+	}}
+	dev, _ := Open("COM1", port)
+	if err := dev.SetBandwidth(100); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported without bandwidth control, got %v", err)
+	}
+
+	dev.hardwareInfo = getHardwareInfo(VariantVH)
+	if err := dev.SetBandwidth(100); err != nil {
+		t.Fatalf("SetBandwidth failed: %v", err)
+	}
+	if last := port.Written[len(port.Written)-1]; last != "bandwidth 100" {
+		t.Errorf("SetBandwidth wrote %q", last)
+	}
+	if err := dev.SetBandwidth(150); err == nil {
+		t.Error("Expected error for a bandwidth outside the allowed set")
+	}
+}
+
+func TestDevice_FirmwareVersion(t *testing.T) {
+	tests := []struct {
+		output              string
+		major, minor, patch int
+		wantErr             bool
+	}{
+		{"1.2.27", 1, 2, 27, false},
+		{"0.9.3-4-g1b2c3d4", 0, 9, 3, false},
+		{"tinySA_v1.4", 1, 4, 0, false},
+		{"20201013", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		port := &ScriptedSerialPort{Responses: map[string]string{
+			"version": shellResponse("version", tt.output),
+		}}
+		dev, _ := Open("COM1", port)
+		major, minor, patch, raw, err := dev.FirmwareVersion()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tt.output, err, tt.wantErr)
+		}
+		if major != tt.major || minor != tt.minor || patch != tt.patch || raw != tt.output {
+			t.Errorf("%q: got %d.%d.%d raw %q", tt.output, major, minor, patch, raw)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package nanovna
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// gnuplotValue formats v for a gnuplot data file. Infinities and NaN are
+// written as "NaN", which gnuplot treats as a missing point.
+func gnuplotValue(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', 8, 64)
+}
+
+// WriteGnuplot writes the sweep as a whitespace-separated data file that
+// gnuplot reads directly, for example with `plot 'file' using 1:2`. The
+// columns are frequency in MHz, S11 return loss in dB, VSWR, S21 magnitude
+// in dB, S11 phase in degrees and the load resistance and reactance in
+// ohms, described by a "#" comment header. The S21 column is NaN when the
+// sweep has no S21. z0 is the reference impedance for the resistance and
+// reactance columns and is recorded in the header; zero or negative uses
+// the sweep's own Z0.
+func (d SweepData) WriteGnuplot(w io.Writer, z0 float64) error {
+	if len(d.S11) != len(d.Frequencies) {
+		return errors.New("frequency and S11 lengths differ")
+	}
+	if z0 <= 0 {
+		z0 = d.referenceZ0()
+	}
+	hasS21 := len(d.S21) == len(d.S11)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# NanoVNA sweep, %d points, Z0 = %g ohm\n", len(d.Frequencies), z0)
+	bw.WriteString("# freq_MHz returnLoss_dB vswr s21_dB phase_deg resistance_ohm reactance_ohm\n")
+	for i, f := range d.Frequencies {
+		g := d.S11[i]
+		s21 := math.NaN()
+		if hasS21 {
+			s21 = magnitudeDB(d.S21[i])
+		}
+		z := gammaToImpedance(g, z0)
+		fmt.Fprintf(bw, "%s %s %s %s %s %s %s\n",
+			gnuplotValue(f/1e6), gnuplotValue(returnLossDB(g)), gnuplotValue(reflectionVSWR(g)),
+			gnuplotValue(s21), gnuplotValue(phaseDegrees(g)), gnuplotValue(real(z)), gnuplotValue(imag(z)))
+	}
+	return bw.Flush()
+}
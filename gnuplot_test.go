@@ -0,0 +1,37 @@
+package nanovna
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSweepData_WriteGnuplot(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 2e6},
+		S11:         []complex128{0.1, 0},
+	}
+	var buf bytes.Buffer
+	if err := d.WriteGnuplot(&buf, 0); err != nil {
+		t.Fatalf("WriteGnuplot failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], "# freq_MHz") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	if lines[2] != "1 20 1.2222222 NaN 0 61.111111 0" {
+		t.Errorf("row 1 = %q", lines[2])
+	}
+	if lines[3] != "2 NaN 1 NaN 0 50 0" {
+		t.Errorf("row 2 = %q", lines[3])
+	}
+
+	buf.Reset()
+	if err := d.WriteGnuplot(&buf, 75); err != nil {
+		t.Fatalf("WriteGnuplot failed: %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], "Z0 = 75 ohm") || lines[3] != "2 NaN 1 NaN 0 75 0" {
+		t.Errorf("unexpected output with z0 = 75:\n%s", buf.String())
+	}
+}
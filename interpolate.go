@@ -0,0 +1,217 @@
+package nanovna
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// bracket locates f within ascending freqs, returning the index i of the
+// lower neighbour and the fractional position t in [0, 1] between freqs[i]
+// and freqs[i+1]. ok is false when f is outside the grid.
+func bracket(freqs []float64, f float64) (i int, t float64, ok bool) {
+	n := len(freqs)
+	if n == 0 || f < freqs[0] || f > freqs[n-1] {
+		return 0, 0, false
+	}
+	if n == 1 {
+		return 0, 0, true
+	}
+	i = sort.SearchFloat64s(freqs, f)
+	if i == 0 {
+		return 0, 0, true
+	}
+	if i >= n {
+		i = n - 1
+	}
+	i--
+	span := freqs[i+1] - freqs[i]
+	if span == 0 {
+		return i, 0, true
+	}
+	return i, (f - freqs[i]) / span, true
+}
+
+// lerpComplex linearly interpolates between a and b in the complex plane.
+func lerpComplex(a, b complex128, t float64) complex128 {
+	return a + complex(t, 0)*(b-a)
+}
+
+// interpolateAt returns v interpolated at position (i, t) as found by bracket.
+func interpolateAt(v []complex128, i int, t float64) complex128 {
+	if i+1 >= len(v) {
+		return v[i]
+	}
+	return lerpComplex(v[i], v[i+1], t)
+}
+
+// MarkerAt returns a marker readout at exactly freqHz, linearly interpolating
+// S11 (and S21 when present) between the neighbouring sweep points in the
+// complex plane. z0 is the reference impedance for the impedance value.
+// It returns an error if freqHz lies outside the swept range.
+func (d SweepData) MarkerAt(freqHz float64, z0 float64) (MarkerRow, error) {
+	if len(d.S11) != len(d.Frequencies) || len(d.S11) == 0 {
+		return MarkerRow{}, fmt.Errorf("sweep has %d frequencies and %d S11 points",
+			len(d.Frequencies), len(d.S11))
+	}
+	if !sort.Float64sAreSorted(d.Frequencies) {
+		d = d.SortByFrequency()
+	}
+
+	i, t, ok := bracket(d.Frequencies, freqHz)
+	if !ok {
+		return MarkerRow{}, fmt.Errorf("frequency %.0f Hz outside swept range %.0f-%.0f Hz",
+			freqHz, d.Frequencies[0], d.Frequencies[len(d.Frequencies)-1])
+	}
+
+	row := newMarkerRow(freqHz, interpolateAt(d.S11, i, t), z0)
+	if len(d.S21) == len(d.Frequencies) {
+		row.setS21(interpolateAt(d.S21, i, t))
+	}
+	return row, nil
+}
+
+// InterpolateTo returns the sweep linearly interpolated (in the complex
+// plane) onto freqs, which must lie within the swept range. S21, S12 and
+// S22 are each interpolated only when present with one value per
+// frequency; otherwise the result has nil for them rather than fabricated
+// values.
+func (d SweepData) InterpolateTo(freqs []float64) (SweepData, error) {
+	if len(d.S11) != len(d.Frequencies) || len(d.S11) == 0 {
+		return SweepData{}, fmt.Errorf("sweep has %d frequencies and %d S11 points",
+			len(d.Frequencies), len(d.S11))
+	}
+	if !sort.Float64sAreSorted(d.Frequencies) {
+		d = d.SortByFrequency()
+	}
+
+	out := SweepData{
+		Frequencies: append([]float64(nil), freqs...),
+		Z0:          d.Z0,
+	}
+	traces := []struct {
+		src []complex128
+		dst *[]complex128
+	}{
+		{d.S11, &out.S11}, {d.S21, &out.S21}, {d.S12, &out.S12}, {d.S22, &out.S22},
+	}
+	for _, tr := range traces {
+		if len(tr.src) == len(d.Frequencies) {
+			*tr.dst = make([]complex128, len(freqs))
+		}
+	}
+	for k, f := range freqs {
+		i, t, ok := bracket(d.Frequencies, f)
+		if !ok {
+			return SweepData{}, fmt.Errorf("frequency %.0f Hz outside swept range %.0f-%.0f Hz",
+				f, d.Frequencies[0], d.Frequencies[len(d.Frequencies)-1])
+		}
+		for _, tr := range traces {
+			if *tr.dst != nil {
+				(*tr.dst)[k] = interpolateAt(tr.src, i, t)
+			}
+		}
+	}
+	return out, nil
+}
+
+// ResampleUniform returns the sweep interpolated onto points equally spaced
+// frequencies spanning the original range, with the same S21, S12 and S22
+// handling as InterpolateTo.
+func (d SweepData) ResampleUniform(points int) (SweepData, error) {
+	if points < 2 {
+		return SweepData{}, fmt.Errorf("need at least 2 points, got %d", points)
+	}
+	if len(d.Frequencies) == 0 {
+		return SweepData{}, fmt.Errorf("sweep has no frequencies")
+	}
+	lo, hi := d.Frequencies[0], d.Frequencies[0]
+	for _, f := range d.Frequencies {
+		lo, hi = min(lo, f), max(hi, f)
+	}
+
+	freqs := make([]float64, points)
+	step := (hi - lo) / float64(points-1)
+	for i := range freqs {
+		freqs[i] = lo + float64(i)*step
+	}
+	freqs[points-1] = hi // avoid rounding past the last point
+	return d.InterpolateTo(freqs)
+}
+
+// RejectionAt returns how far |S21| at targetHz lies below |S21| at refHz, in
+// dB, for filter skirt analysis: a passband reference and a stopband target
+// 40 dB down give 40. Both values are interpolated as in InterpolateTo. It
+// returns an error when the sweep has no S21 or either frequency lies outside
+// the swept range.
+func (d SweepData) RejectionAt(refHz, targetHz float64) (float64, error) {
+	if len(d.S21) == 0 || len(d.S21) != len(d.Frequencies) {
+		return 0, fmt.Errorf("sweep has no S21 data")
+	}
+	pts, err := d.InterpolateTo([]float64{refHz, targetHz})
+	if err != nil {
+		return 0, err
+	}
+	return magnitudeDB(pts.S21[0]) - magnitudeDB(pts.S21[1]), nil
+}
+
+// InterpolateTo returns the calibration's error terms interpolated onto
+// freqs, so that one wide calibration can correct narrower or differently
+// sampled sweeps. Magnitude and unwrapped phase are interpolated linearly
+// and separately, which follows the rotation of terms with electrical delay
+// better than interpolating in the complex plane. freqs must lie within the
+// calibrated range; absent through terms stay absent.
+func (c CalibrationData) InterpolateTo(freqs []float64) (CalibrationData, error) {
+	if !c.Valid() {
+		return CalibrationData{}, errors.New("calibration is not valid")
+	}
+	if !sort.Float64sAreSorted(c.Frequencies) {
+		return CalibrationData{}, errors.New("calibration frequencies are not ascending")
+	}
+	n := len(c.Frequencies)
+	pos := make([]struct {
+		i int
+		t float64
+	}, len(freqs))
+	for k, f := range freqs {
+		i, t, ok := bracket(c.Frequencies, f)
+		if !ok {
+			return CalibrationData{}, fmt.Errorf("frequency %.0f Hz outside calibrated range %.0f-%.0f Hz",
+				f, c.Frequencies[0], c.Frequencies[n-1])
+		}
+		pos[k].i, pos[k].t = i, t
+	}
+
+	polar := func(v []complex128) []complex128 {
+		if len(v) == 0 {
+			return nil
+		}
+		mag := make([]float64, len(v))
+		phase := make([]float64, len(v))
+		for i, x := range v {
+			mag[i], phase[i] = cmplx.Abs(x), cmplx.Phase(x)
+		}
+		phase = UnwrapPhase(phase)
+		out := make([]complex128, len(freqs))
+		for k, p := range pos {
+			m, ph := mag[p.i], phase[p.i]
+			if p.i+1 < len(v) {
+				m += p.t * (mag[p.i+1] - m)
+				ph += p.t * (phase[p.i+1] - ph)
+			}
+			out[k] = cmplx.Rect(m, math.Remainder(ph, 2*math.Pi))
+		}
+		return out
+	}
+	return CalibrationData{
+		Frequencies:          append([]float64(nil), freqs...),
+		Directivity:          polar(c.Directivity),
+		SourceMatch:          polar(c.SourceMatch),
+		ReflectionTracking:   polar(c.ReflectionTracking),
+		TransmissionTracking: polar(c.TransmissionTracking),
+		Isolation:            polar(c.Isolation),
+		Standards:            append([]CalStandard(nil), c.Standards...),
+	}, nil
+}
@@ -0,0 +1,137 @@
+package nanovna
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestSweepData_MarkerAt(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 2e6, 3e6},
+		S11:         []complex128{0, complex(0.2, 0), complex(0.4, 0)},
+		S21:         []complex128{1, 1, 1},
+	}
+	row, err := d.MarkerAt(1.5e6, 50)
+	if err != nil {
+		t.Fatalf("MarkerAt failed: %v", err)
+	}
+	// Halfway between 0 and 0.2 gives Γ = 0.1
+	if math.Abs(row.VSWR-1.1/0.9) > 1e-9 {
+		t.Errorf("VSWR = %v, want %v", row.VSWR, 1.1/0.9)
+	}
+	if row.S21MagDB != 0 {
+		t.Errorf("S21MagDB = %v, want 0", row.S21MagDB)
+	}
+
+	if _, err := d.MarkerAt(4e6, 50); err == nil {
+		t.Error("Expected error for frequency outside the sweep")
+	}
+
+	d.S21 = nil
+	row, _ = d.MarkerAt(3e6, 50)
+	if !math.IsNaN(row.S21MagDB) {
+		t.Error("S21MagDB should be NaN without S21 data")
+	}
+}
+
+func TestSweepData_ResampleUniform_S11Only(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 3e6},
+		S11:         []complex128{0, complex(0.2, 0.2)},
+	}
+	got, err := d.ResampleUniform(3)
+	if err != nil {
+		t.Fatalf("ResampleUniform failed: %v", err)
+	}
+	want := SweepData{
+		Frequencies: []float64{1e6, 2e6, 3e6},
+		S11:         []complex128{0, complex(0.1, 0.1), complex(0.2, 0.2)},
+	}
+	if !got.Equal(want, 1e-6, 1e-12) {
+		t.Errorf("ResampleUniform() = %+v, want %+v", got, want)
+	}
+	if got.S21 != nil {
+		t.Error("S21 must stay nil for S11-only data")
+	}
+}
+
+func TestSweepData_InterpolateTo_WithS21(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 3e6},
+		S11:         []complex128{0, 0.2},
+		S21:         []complex128{1, complex(0, 1)},
+	}
+	got, err := d.InterpolateTo([]float64{2e6})
+	if err != nil {
+		t.Fatalf("InterpolateTo failed: %v", err)
+	}
+	if len(got.S21) != 1 || got.S21[0] != complex(0.5, 0.5) {
+		t.Errorf("S21 = %v, want [(0.5+0.5i)]", got.S21)
+	}
+
+	// Mismatched S21 is dropped rather than padded
+	d.S21 = d.S21[:1]
+	got, _ = d.InterpolateTo([]float64{2e6})
+	if got.S21 != nil {
+		t.Errorf("mismatched S21 should become nil, got %v", got.S21)
+	}
+
+	if _, err := d.InterpolateTo([]float64{5e6}); err == nil {
+		t.Error("Expected error for frequency outside the sweep")
+	}
+}
+
+func TestSweepData_RejectionAt(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 2e6, 3e6},
+		S11:         []complex128{0, 0, 0},
+		S21:         []complex128{1, 0.5, 0.01},
+	}
+	db, err := d.RejectionAt(1e6, 3e6)
+	if err != nil {
+		t.Fatalf("RejectionAt failed: %v", err)
+	}
+	if math.Abs(db-40) > 1e-9 {
+		t.Errorf("RejectionAt(1e6, 3e6) = %v, want 40", db)
+	}
+
+	if _, err := d.RejectionAt(1e6, 4e6); err == nil {
+		t.Error("Expected error for target outside the sweep")
+	}
+	d.S21 = nil
+	if _, err := d.RejectionAt(1e6, 2e6); err == nil {
+		t.Error("Expected error without S21")
+	}
+}
+
+func TestCalibrationData_InterpolateTo(t *testing.T) {
+	deg := math.Pi / 180
+	cal := CalibrationData{
+		Frequencies:        []float64{1e6, 2e6, 3e6},
+		Directivity:        []complex128{cmplx.Rect(0.1, 0), cmplx.Rect(0.1, -100*deg), cmplx.Rect(0.1, -200*deg)},
+		SourceMatch:        []complex128{0.1, 0.1, 0.3},
+		ReflectionTracking: []complex128{1, 1, 1},
+	}
+	got, err := cal.InterpolateTo([]float64{1e6, 2.5e6})
+	if err != nil {
+		t.Fatalf("InterpolateTo failed: %v", err)
+	}
+	if !got.Valid() || len(got.Frequencies) != 2 || got.HasThrough() || got.Isolation != nil {
+		t.Fatalf("unexpected calibration %+v", got)
+	}
+	// The phase crosses ±180° between 2 and 3 MHz; unwrapped it is -150°
+	if want := cmplx.Rect(0.1, -150*deg); cmplx.Abs(got.Directivity[1]-want) > 1e-12 {
+		t.Errorf("directivity at 2.5 MHz = %v, want %v", got.Directivity[1], want)
+	}
+	if cmplx.Abs(got.SourceMatch[1]-0.2) > 1e-12 || cmplx.Abs(got.Directivity[0]-0.1) > 1e-12 {
+		t.Errorf("unexpected terms %v, %v", got.Directivity, got.SourceMatch)
+	}
+
+	if _, err := cal.InterpolateTo([]float64{4e6}); err == nil {
+		t.Error("Expected error outside the calibrated range")
+	}
+	if _, err := (CalibrationData{}).InterpolateTo([]float64{1e6}); err == nil {
+		t.Error("Expected error for an invalid calibration")
+	}
+}
@@ -0,0 +1,78 @@
+package nanovna
+
+import "sync"
+
+// fairLock is a mutual-exclusion lock for serializing access to the serial
+// port. Unlike sync.Mutex, which lets a goroutine that has just unlocked
+// immediately re-acquire the lock ahead of long-standing waiters, fairLock
+// gives these guarantees:
+//
+//   - Waiters are granted the lock in arrival (FIFO) order; a goroutine
+//     running a tight sweep loop re-queues behind everyone already waiting.
+//   - Priority waiters (lockPriority) are granted the lock before any normal
+//     waiter, in FIFO order among themselves. They wait at most for the
+//     current holder and earlier priority waiters, which keeps short
+//     interactive operations such as screen capture responsive.
+//   - On unlock the lock is handed directly to the next waiter, so a newly
+//     arriving goroutine cannot barge in ahead of it.
+//
+// Normal waiters can be delayed indefinitely only by a continuous stream of
+// priority acquisitions, so priority should be reserved for brief operations.
+// The zero value is an unlocked lock.
+type fairLock struct {
+	mu       sync.Mutex
+	held     bool
+	priority []chan struct{}
+	normal   []chan struct{}
+}
+
+// lock acquires the lock as a normal waiter.
+func (l *fairLock) lock() {
+	l.acquire(false)
+}
+
+// lockPriority acquires the lock ahead of all normal waiters.
+func (l *fairLock) lockPriority() {
+	l.acquire(true)
+}
+
+// acquire takes the lock immediately when it is free and nobody is queued,
+// and otherwise queues and waits to be handed the lock.
+func (l *fairLock) acquire(priority bool) {
+	l.mu.Lock()
+	if !l.held {
+		l.held = true
+		l.mu.Unlock()
+		return
+	}
+	ready := make(chan struct{})
+	if priority {
+		l.priority = append(l.priority, ready)
+	} else {
+		l.normal = append(l.normal, ready)
+	}
+	l.mu.Unlock()
+	<-ready
+}
+
+// unlock releases the lock, handing it to the next waiter if there is one.
+func (l *fairLock) unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.held {
+		panic("nanovna: unlock of unlocked fairLock")
+	}
+
+	var next chan struct{}
+	switch {
+	case len(l.priority) > 0:
+		next, l.priority = l.priority[0], l.priority[1:]
+	case len(l.normal) > 0:
+		next, l.normal = l.normal[0], l.normal[1:]
+	default:
+		l.held = false
+		return
+	}
+	// Ownership passes directly; held stays true
+	close(next)
+}
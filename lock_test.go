@@ -0,0 +1,46 @@
+package nanovna
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFairLock_Ordering(t *testing.T) {
+	var l fairLock
+	l.lock()
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	start := func(name string, priority bool) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if priority {
+				l.lockPriority()
+			} else {
+				l.lock()
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			l.unlock()
+		}()
+		// Let the goroutine queue before starting the next one
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	start("sweep1", false)
+	start("sweep2", false)
+	start("capture", true)
+	l.unlock()
+	wg.Wait()
+
+	want := []string{"capture", "sweep1", "sweep2"}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("acquisition order = %v, want %v", order, want)
+		}
+	}
+}
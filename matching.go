@@ -0,0 +1,148 @@
+package nanovna
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// ComponentKind identifies the type of a lumped matching component.
+type ComponentKind int
+
+const (
+	ComponentNone      ComponentKind = iota // No component (short for series, open for shunt)
+	ComponentInductor                       // Inductor, Value in henries
+	ComponentCapacitor                      // Capacitor, Value in farads
+)
+
+// String returns the string representation of the component kind.
+func (ck ComponentKind) String() string {
+	switch ck {
+	case ComponentInductor:
+		return "Inductor"
+	case ComponentCapacitor:
+		return "Capacitor"
+	default:
+		return "None"
+	}
+}
+
+// Component is a single lumped element of a matching network.
+type Component struct {
+	Kind      ComponentKind
+	Value     float64 // Henries for inductors, farads for capacitors
+	Reactance float64 // Reactance in ohms at the design frequency
+}
+
+// String returns the component value in engineering units.
+func (c Component) String() string {
+	switch c.Kind {
+	case ComponentInductor:
+		return fmt.Sprintf("%.4g nH", c.Value*1e9)
+	case ComponentCapacitor:
+		return fmt.Sprintf("%.4g pF", c.Value*1e12)
+	default:
+		return "none"
+	}
+}
+
+// LNetwork is one L-section solution matching a load to the reference impedance.
+type LNetwork struct {
+	Series Component
+	Shunt  Component
+	// ShuntAtLoad is true when the shunt element sits directly across the
+	// load with the series element towards the source, and false when the
+	// series element is next to the load and the shunt is across the source.
+	ShuntAtLoad bool
+}
+
+// MatchLNetwork computes an L-network matching zLoad to a z0 source at freqHz.
+// It returns the first solution found by MatchLNetworkSolutions; use that
+// function to inspect every topology and sign choice.
+func MatchLNetwork(zLoad complex128, z0, freqHz float64) (series, shunt Component, err error) {
+	solutions, err := MatchLNetworkSolutions(zLoad, z0, freqHz)
+	if err != nil {
+		return Component{}, Component{}, err
+	}
+	return solutions[0].Series, solutions[0].Shunt, nil
+}
+
+// MatchLNetworkSolutions returns every L-network that matches zLoad to z0 at
+// freqHz. Each valid topology has two solutions (the ± branches of the
+// quadratic), so up to four networks are returned.
+func MatchLNetworkSolutions(zLoad complex128, z0, freqHz float64) ([]LNetwork, error) {
+	if z0 <= 0 {
+		return nil, errors.New("reference impedance must be positive")
+	}
+	if freqHz <= 0 {
+		return nil, errors.New("frequency must be positive")
+	}
+	r, x := real(zLoad), imag(zLoad)
+	if r <= 0 || cmplx.IsNaN(zLoad) || cmplx.IsInf(zLoad) {
+		return nil, fmt.Errorf("cannot match load %v: resistance must be positive and finite", zLoad)
+	}
+
+	omega := 2 * math.Pi * freqHz
+	var solutions []LNetwork
+
+	// Shunt element across the load (requires R² + X² >= Z0·R).
+	if disc := r*r + x*x - z0*r; disc >= 0 {
+		root := math.Sqrt(r/z0) * math.Sqrt(disc)
+		for _, sign := range []float64{1, -1} {
+			b := (x + sign*root) / (r*r + x*x)
+			xs := 1/b + x*z0/r - z0/(b*r)
+			if b == 0 {
+				xs = -x
+			}
+			solutions = append(solutions, LNetwork{
+				Series:      seriesComponent(xs, omega),
+				Shunt:       shuntComponent(b, omega),
+				ShuntAtLoad: true,
+			})
+		}
+	}
+
+	// Series element next to the load (requires R <= Z0).
+	if r <= z0 {
+		root := math.Sqrt(r * (z0 - r))
+		for _, sign := range []float64{1, -1} {
+			xs := sign*root - x
+			b := sign * math.Sqrt((z0-r)/r) / z0
+			solutions = append(solutions, LNetwork{
+				Series:      seriesComponent(xs, omega),
+				Shunt:       shuntComponent(b, omega),
+				ShuntAtLoad: false,
+			})
+		}
+	}
+
+	if len(solutions) == 0 {
+		return nil, fmt.Errorf("no L-network solution for load %v", zLoad)
+	}
+	return solutions, nil
+}
+
+// seriesComponent converts a series reactance to an inductor or capacitor.
+func seriesComponent(x, omega float64) Component {
+	switch {
+	case x > 0:
+		return Component{Kind: ComponentInductor, Value: x / omega, Reactance: x}
+	case x < 0:
+		return Component{Kind: ComponentCapacitor, Value: -1 / (omega * x), Reactance: x}
+	default:
+		return Component{Kind: ComponentNone}
+	}
+}
+
+// shuntComponent converts a shunt susceptance to a capacitor or inductor.
+func shuntComponent(b, omega float64) Component {
+	switch {
+	case b > 0:
+		return Component{Kind: ComponentCapacitor, Value: b / omega, Reactance: -1 / b}
+	case b < 0:
+		return Component{Kind: ComponentInductor, Value: -1 / (omega * b), Reactance: -1 / b}
+	default:
+		return Component{Kind: ComponentNone, Reactance: math.Inf(1)}
+	}
+}
@@ -0,0 +1,47 @@
+package nanovna
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestMatchLNetworkSolutions_MatchToZ0(t *testing.T) {
+	const z0, freq = 50.0, 14.2e6
+	omega := 2 * math.Pi * freq
+	loads := []complex128{complex(200, 50), complex(10, -20), complex(25, 0), complex(75, 30)}
+	for _, zl := range loads {
+		solutions, err := MatchLNetworkSolutions(zl, z0, freq)
+		if err != nil {
+			t.Fatalf("MatchLNetworkSolutions(%v) failed: %v", zl, err)
+		}
+		for _, n := range solutions {
+			zs := complex(0, n.Series.Reactance)
+			var ys complex128
+			if n.Shunt.Kind != ComponentNone {
+				ys = complex(0, -1/n.Shunt.Reactance)
+			}
+			var zin complex128
+			if n.ShuntAtLoad {
+				zin = zs + 1/(ys+1/zl)
+			} else {
+				zin = 1 / (ys + 1/(zs+zl))
+			}
+			if cmplx.Abs(zin-complex(z0, 0)) > 1e-6 {
+				t.Errorf("load %v: network %+v gives Zin %v, want %v", zl, n, zin, z0)
+			}
+			if n.Series.Kind == ComponentInductor && math.Abs(n.Series.Value*omega-n.Series.Reactance) > 1e-9 {
+				t.Errorf("inductor value %g inconsistent with reactance %g", n.Series.Value, n.Series.Reactance)
+			}
+		}
+	}
+}
+
+func TestMatchLNetwork_InvalidInput(t *testing.T) {
+	if _, _, err := MatchLNetwork(complex(-5, 0), 50, 1e6); err == nil {
+		t.Error("Expected error for negative resistance")
+	}
+	if _, _, err := MatchLNetwork(complex(50, 0), 50, 0); err == nil {
+		t.Error("Expected error for zero frequency")
+	}
+}
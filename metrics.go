@@ -0,0 +1,412 @@
+package nanovna
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// maxImpedance is the impedance magnitude reported for a reflection
+// coefficient of exactly 1, where Z = Z0·(1+Γ)/(1−Γ) is singular.
+const maxImpedance = 1e12
+
+// reflectionVSWR returns the VSWR for reflection coefficient g, or +Inf when
+// |g| >= 1.
+func reflectionVSWR(g complex128) float64 {
+	mag := cmplx.Abs(g)
+	if mag >= 1 {
+		return math.Inf(1)
+	}
+	return (1 + mag) / (1 - mag)
+}
+
+// returnLossDB returns −20·log10|g|, which is +Inf for a perfect match.
+func returnLossDB(g complex128) float64 {
+	mag := cmplx.Abs(g)
+	if mag == 0 {
+		return math.Inf(1)
+	}
+	return -20 * math.Log10(mag)
+}
+
+// mismatchLossDB returns −10·log10(1−|g|²), the power lost to reflection,
+// or +Inf when |g| >= 1.
+func mismatchLossDB(g complex128) float64 {
+	mag := cmplx.Abs(g)
+	if mag >= 1 {
+		return math.Inf(1)
+	}
+	return -10 * math.Log10(1-mag*mag)
+}
+
+// gammaToImpedance maps a reflection coefficient to impedance relative to z0.
+// Γ = 1 yields maxImpedance (a finite stand-in for an open circuit).
+func gammaToImpedance(g complex128, z0 float64) complex128 {
+	if g == 1 {
+		return complex(maxImpedance, 0)
+	}
+	z := complex(z0, 0) * (1 + g) / (1 - g)
+	if cmplx.Abs(z) > maxImpedance || cmplx.IsInf(z) || cmplx.IsNaN(z) {
+		return complex(maxImpedance, 0)
+	}
+	return z
+}
+
+// magnitudeDB returns 20·log10|v|, floored at -200 dB so that exact zeros
+// still compare as finite values.
+func magnitudeDB(v complex128) float64 {
+	return 20 * math.Log10(math.Max(cmplx.Abs(v), 1e-10))
+}
+
+// phaseDegrees returns the phase of v in degrees in (−180, 180].
+func phaseDegrees(v complex128) float64 {
+	return cmplx.Phase(v) * 180 / math.Pi
+}
+
+// SeriesKind selects a derived quantity for SweepData.Series.
+type SeriesKind int
+
+const (
+	SeriesReturnLossDB  SeriesKind = iota // S11 return loss in dB
+	SeriesVSWR                            // S11 voltage standing wave ratio
+	SeriesS21DB                           // S21 magnitude in dB
+	SeriesPhaseDeg                        // S11 phase in degrees
+	SeriesImpedanceReal                   // Load resistance in ohms
+	SeriesImpedanceImag                   // Load reactance in ohms
+	SeriesSmithX                          // Real part of S11 (Smith chart x)
+	SeriesSmithY                          // Imaginary part of S11 (Smith chart y)
+)
+
+// String returns the string representation of the series kind.
+func (sk SeriesKind) String() string {
+	switch sk {
+	case SeriesReturnLossDB:
+		return "Return Loss (dB)"
+	case SeriesVSWR:
+		return "VSWR"
+	case SeriesS21DB:
+		return "S21 (dB)"
+	case SeriesPhaseDeg:
+		return "S11 Phase (deg)"
+	case SeriesImpedanceReal:
+		return "Resistance (ohm)"
+	case SeriesImpedanceImag:
+		return "Reactance (ohm)"
+	case SeriesSmithX:
+		return "Smith X"
+	case SeriesSmithY:
+		return "Smith Y"
+	default:
+		return "Unknown"
+	}
+}
+
+// Series returns x (frequency in Hz) and y (the requested quantity) arrays
+// for plotting. z0 is the reference impedance used for impedance kinds;
+// zero or negative uses the sweep's own Z0 (DefaultZ0 unless set). Both
+// arrays are empty when the kind is unknown or its source data is
+// unavailable, such as S21 kinds on a sweep without S21.
+func (d SweepData) Series(kind SeriesKind, z0 float64) ([]float64, []float64) {
+	src := d.S11
+	if kind == SeriesS21DB {
+		src = d.S21
+	}
+	if len(src) == 0 || len(src) != len(d.Frequencies) {
+		return nil, nil
+	}
+	if z0 <= 0 {
+		z0 = d.referenceZ0()
+	}
+
+	var value func(complex128) float64
+	switch kind {
+	case SeriesReturnLossDB:
+		value = returnLossDB
+	case SeriesVSWR:
+		value = reflectionVSWR
+	case SeriesS21DB:
+		value = magnitudeDB
+	case SeriesPhaseDeg:
+		value = phaseDegrees
+	case SeriesImpedanceReal:
+		value = func(g complex128) float64 { return real(gammaToImpedance(g, z0)) }
+	case SeriesImpedanceImag:
+		value = func(g complex128) float64 { return imag(gammaToImpedance(g, z0)) }
+	case SeriesSmithX:
+		value = func(g complex128) float64 { return real(g) }
+	case SeriesSmithY:
+		value = func(g complex128) float64 { return imag(g) }
+	default:
+		return nil, nil
+	}
+
+	x := append([]float64(nil), d.Frequencies...)
+	y := make([]float64, len(src))
+	for i, v := range src {
+		y[i] = value(v)
+	}
+	return x, y
+}
+
+// MarkerRow is a marker readout at one frequency.
+type MarkerRow struct {
+	FreqHz      float64
+	MagDB       float64 // |S11| in dB
+	AngleDeg    float64 // S11 phase in degrees
+	VSWR        float64
+	Impedance   complex128 // Load impedance in ohms
+	S21MagDB    float64    // |S21| in dB, NaN when the sweep has no S21
+	S21AngleDeg float64    // S21 phase in degrees, NaN when the sweep has no S21
+}
+
+// newMarkerRow computes a marker readout from reflection coefficient g.
+func newMarkerRow(freq float64, g complex128, z0 float64) MarkerRow {
+	return MarkerRow{
+		FreqHz:      freq,
+		MagDB:       magnitudeDB(g),
+		AngleDeg:    phaseDegrees(g),
+		VSWR:        reflectionVSWR(g),
+		Impedance:   gammaToImpedance(g, z0),
+		S21MagDB:    math.NaN(),
+		S21AngleDeg: math.NaN(),
+	}
+}
+
+// setS21 fills the transmission columns of the row.
+func (r *MarkerRow) setS21(s21 complex128) {
+	r.S21MagDB = magnitudeDB(s21)
+	r.S21AngleDeg = phaseDegrees(s21)
+}
+
+// S11MarkerTable returns a marker readout for every sweep point, using the
+// sweep's reference impedance for the impedance column. S21 columns are
+// filled when the sweep has S21 data.
+func (d SweepData) S11MarkerTable() []MarkerRow {
+	n := len(d.S11)
+	if len(d.Frequencies) < n {
+		n = len(d.Frequencies)
+	}
+	z0 := d.referenceZ0()
+	rows := make([]MarkerRow, n)
+	for i := 0; i < n; i++ {
+		rows[i] = newMarkerRow(d.Frequencies[i], d.S11[i], z0)
+		if len(d.S21) == len(d.S11) {
+			rows[i].setS21(d.S21[i])
+		}
+	}
+	return rows
+}
+
+// VSWRBandwidth returns the contiguous frequency span around the minimum-VSWR
+// point over which S11 VSWR stays below maxVSWR, the usual antenna bandwidth
+// figure. Each edge is linearly interpolated between the last point inside
+// and the first point outside the limit; an edge that reaches the end of the
+// sweep is reported at the sweep limit. ok is false when VSWR never drops
+// below maxVSWR.
+func (d SweepData) VSWRBandwidth(maxVSWR float64) (lowHz, highHz, bwHz float64, ok bool) {
+	if len(d.S11) == 0 || len(d.S11) != len(d.Frequencies) {
+		return 0, 0, 0, false
+	}
+	if !sort.Float64sAreSorted(d.Frequencies) {
+		d = d.SortByFrequency()
+	}
+
+	vswr := make([]float64, len(d.S11))
+	best := 0
+	for i, g := range d.S11 {
+		vswr[i] = reflectionVSWR(g)
+		if vswr[i] < vswr[best] {
+			best = i
+		}
+	}
+	if !(vswr[best] < maxVSWR) {
+		return 0, 0, 0, false
+	}
+
+	// edge interpolates the crossing between inside point i and outside point o
+	edge := func(i, o int) float64 {
+		t := (maxVSWR - vswr[i]) / (vswr[o] - vswr[i])
+		return d.Frequencies[i] + t*(d.Frequencies[o]-d.Frequencies[i])
+	}
+
+	lo := best
+	for lo > 0 && vswr[lo-1] < maxVSWR {
+		lo--
+	}
+	lowHz = d.Frequencies[lo]
+	if lo > 0 {
+		lowHz = edge(lo, lo-1)
+	}
+
+	hi := best
+	for hi < len(vswr)-1 && vswr[hi+1] < maxVSWR {
+		hi++
+	}
+	highHz = d.Frequencies[hi]
+	if hi < len(vswr)-1 {
+		highHz = edge(hi, hi+1)
+	}
+
+	return lowHz, highHz, highHz - lowHz, true
+}
+
+// MismatchLossDB returns the mismatch loss in dB at each S11 point,
+// −10·log10(1−|Γ|²): the share of incident power reflected rather than
+// delivered to the load. Points with |Γ| >= 1 give +Inf.
+func (d SweepData) MismatchLossDB() []float64 {
+	out := make([]float64, len(d.S11))
+	for i, g := range d.S11 {
+		out[i] = mismatchLossDB(g)
+	}
+	return out
+}
+
+// InsertionLossStats summarizes the insertion loss, −20·log10|S21| in dB, of
+// the points with lowHz <= frequency <= highHz: its mean, maximum and minimum
+// and the flatness (maximum − minimum). ok is false when S21 is absent or no
+// point falls in the window.
+func (d SweepData) InsertionLossStats(lowHz, highHz float64) (meanDB, maxDB, minDB, flatnessDB float64, ok bool) {
+	if len(d.S21) == 0 || len(d.S21) != len(d.Frequencies) {
+		return 0, 0, 0, 0, false
+	}
+
+	n := 0
+	maxDB, minDB = math.Inf(-1), math.Inf(1)
+	for i, f := range d.Frequencies {
+		if f < lowHz || f > highHz {
+			continue
+		}
+		il := -magnitudeDB(d.S21[i])
+		meanDB += il
+		maxDB = math.Max(maxDB, il)
+		minDB = math.Min(minDB, il)
+		n++
+	}
+	if n == 0 {
+		return 0, 0, 0, 0, false
+	}
+	meanDB /= float64(n)
+	return meanDB, maxDB, minDB, maxDB - minDB, true
+}
+
+// VSWR returns the voltage standing wave ratio (1+|Γ|)/(1−|Γ|) at each S11
+// point. Points with |Γ| >= 1, which noisy or active measurements can
+// produce, give +Inf rather than a negative ratio.
+func (d SweepData) VSWR() []float64 {
+	out := make([]float64, len(d.S11))
+	for i, g := range d.S11 {
+		out[i] = reflectionVSWR(g)
+	}
+	return out
+}
+
+// MinVSWR returns the frequency and VSWR of the best-matched S11 point. It
+// returns 0 and +Inf when the sweep has no S11 data for its frequencies.
+func (d SweepData) MinVSWR() (freq float64, vswr float64) {
+	if len(d.S11) == 0 || len(d.S11) != len(d.Frequencies) {
+		return 0, math.Inf(1)
+	}
+	freq, vswr = d.Frequencies[0], reflectionVSWR(d.S11[0])
+	for i, g := range d.S11[1:] {
+		if v := reflectionVSWR(g); v < vswr {
+			freq, vswr = d.Frequencies[i+1], v
+		}
+	}
+	return freq, vswr
+}
+
+// ReturnLossDB returns the return loss −20·log10|Γ| in dB at each S11
+// point. A perfect match (|Γ| = 0) gives +Inf.
+func (d SweepData) ReturnLossDB() []float64 {
+	out := make([]float64, len(d.S11))
+	for i, g := range d.S11 {
+		out[i] = returnLossDB(g)
+	}
+	return out
+}
+
+// ReflectionMagnitude returns |Γ| at each S11 point.
+func (d SweepData) ReflectionMagnitude() []float64 {
+	out := make([]float64, len(d.S11))
+	for i, g := range d.S11 {
+		out[i] = cmplx.Abs(g)
+	}
+	return out
+}
+
+// Impedance returns the load impedance Z = z0·(1+Γ)/(1−Γ) in ohms at each
+// S11 point. z0 is the reference impedance; zero or negative uses the
+// sweep's own Z0 (DefaultZ0 unless set). Γ = 1, where the mapping is
+// singular, and any result beyond maxImpedance are reported as a purely
+// resistive 1e12 Ω.
+func (d SweepData) Impedance(z0 float64) []complex128 {
+	if z0 <= 0 {
+		z0 = d.referenceZ0()
+	}
+	out := make([]complex128, len(d.S11))
+	for i, g := range d.S11 {
+		out[i] = gammaToImpedance(g, z0)
+	}
+	return out
+}
+
+// resonanceBandwidthDB is the drop in return loss from the dip that marks
+// the bandwidth edges used by Resonance.
+const resonanceBandwidthDB = 3.0
+
+// Resonance locates the S11 dip (minimum |Γ|) and estimates the loaded Q as
+// the dip frequency divided by the bandwidth between the points on either
+// side where the return loss has fallen 3 dB below its peak. Each edge is
+// linearly interpolated between sample points. An error is returned for
+// sweeps with fewer than three points or when the dip does not fall by
+// 3 dB on both sides within the sweep.
+func (d SweepData) Resonance() (freq float64, q float64, err error) {
+	if len(d.S11) != len(d.Frequencies) {
+		return 0, 0, errors.New("frequency and S11 lengths differ")
+	}
+	if len(d.S11) < 3 {
+		return 0, 0, fmt.Errorf("need at least 3 points, got %d", len(d.S11))
+	}
+	if !sort.Float64sAreSorted(d.Frequencies) {
+		d = d.SortByFrequency()
+	}
+
+	// −magnitudeDB is floored, so a perfect match still has a finite peak
+	rl := make([]float64, len(d.S11))
+	best := 0
+	for i, g := range d.S11 {
+		rl[i] = -magnitudeDB(g)
+		if rl[i] > rl[best] {
+			best = i
+		}
+	}
+	threshold := rl[best] - resonanceBandwidthDB
+
+	// edge interpolates the crossing between inside point i and outside point o
+	edge := func(i, o int) float64 {
+		t := (rl[i] - threshold) / (rl[i] - rl[o])
+		return d.Frequencies[i] + t*(d.Frequencies[o]-d.Frequencies[i])
+	}
+
+	lo := best
+	for lo > 0 && rl[lo-1] >= threshold {
+		lo--
+	}
+	hi := best
+	for hi < len(rl)-1 && rl[hi+1] >= threshold {
+		hi++
+	}
+	if lo == 0 || hi == len(rl)-1 {
+		return 0, 0, fmt.Errorf("no clear dip: return loss does not fall %g dB on both sides of %g Hz",
+			resonanceBandwidthDB, d.Frequencies[best])
+	}
+
+	bw := edge(hi, hi+1) - edge(lo, lo-1)
+	if bw <= 0 {
+		return 0, 0, errors.New("no clear dip: zero bandwidth")
+	}
+	freq = d.Frequencies[best]
+	return freq, freq / bw, nil
+}
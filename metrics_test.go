@@ -0,0 +1,174 @@
+package nanovna
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestSweepData_Series(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 2e6},
+		S11:         []complex128{complex(1.0/3, 0), 0},
+	}
+	x, y := d.Series(SeriesVSWR, 50)
+	if len(x) != 2 || x[1] != 2e6 {
+		t.Fatalf("unexpected x axis %v", x)
+	}
+	if math.Abs(y[0]-2) > 1e-12 || y[1] != 1 {
+		t.Errorf("VSWR series = %v, want [2 1]", y)
+	}
+
+	_, r := d.Series(SeriesImpedanceReal, 50)
+	if math.Abs(r[0]-100) > 1e-9 || math.Abs(r[1]-50) > 1e-9 {
+		t.Errorf("resistance series = %v, want [100 50]", r)
+	}
+	d.Z0 = 75
+	if _, r := d.Series(SeriesImpedanceReal, 0); math.Abs(r[0]-150) > 1e-9 || math.Abs(r[1]-75) > 1e-9 {
+		t.Errorf("resistance series with default z0 = %v, want [150 75]", r)
+	}
+	d.Z0 = 0
+
+	if x, y := d.Series(SeriesS21DB, 50); len(x) != 0 || len(y) != 0 {
+		t.Error("S21 series should be empty without S21 data")
+	}
+}
+
+func TestSweepData_S11MarkerTable(t *testing.T) {
+	d := SweepData{Frequencies: []float64{7e6}, S11: []complex128{complex(0, 1.0/3)}, Z0: 75}
+	rows := d.S11MarkerTable()
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	r := rows[0]
+	if r.FreqHz != 7e6 || math.Abs(r.AngleDeg-90) > 1e-9 || math.Abs(r.VSWR-2) > 1e-9 {
+		t.Errorf("unexpected marker row %+v", r)
+	}
+	if math.Abs(r.MagDB-20*math.Log10(1.0/3)) > 1e-9 {
+		t.Errorf("MagDB = %v", r.MagDB)
+	}
+	// Z = 75·(1+j/3)/(1−j/3) = 60 + j45
+	if math.Abs(real(r.Impedance)-60) > 1e-9 || math.Abs(imag(r.Impedance)-45) > 1e-9 {
+		t.Errorf("Impedance = %v, want 60+45i", r.Impedance)
+	}
+}
+
+func TestSweepData_VSWRBandwidth(t *testing.T) {
+	// Γ = 1/2, 1/3, 0, 1/3, 1/2 gives VSWR 3, 2, 1, 2, 3
+	d := SweepData{
+		Frequencies: []float64{1e6, 2e6, 3e6, 4e6, 5e6},
+		S11:         []complex128{0.5, complex(1.0/3, 0), 0, complex(0, 1.0/3), -0.5},
+	}
+	low, high, bw, ok := d.VSWRBandwidth(2.5)
+	if !ok {
+		t.Fatal("expected a bandwidth below VSWR 2.5")
+	}
+	if math.Abs(low-1.5e6) > 1e-3 || math.Abs(high-4.5e6) > 1e-3 || math.Abs(bw-3e6) > 1e-3 {
+		t.Errorf("VSWRBandwidth(2.5) = %v, %v, %v; want 1.5e6, 4.5e6, 3e6", low, high, bw)
+	}
+
+	if low, high, _, _ := d.VSWRBandwidth(10); low != 1e6 || high != 5e6 {
+		t.Errorf("edges should clamp to the sweep, got %v-%v", low, high)
+	}
+	if _, _, _, ok := d.VSWRBandwidth(1); ok {
+		t.Error("expected ok=false when VSWR never drops below the limit")
+	}
+}
+
+func TestSweepData_MismatchLossDB(t *testing.T) {
+	d := SweepData{S11: []complex128{0, complex(0, math.Sqrt(0.5)), 1}}
+	got := d.MismatchLossDB()
+	if len(got) != 3 || got[0] != 0 || math.Abs(got[1]-10*math.Log10(2)) > 1e-9 || !math.IsInf(got[2], 1) {
+		t.Errorf("MismatchLossDB() = %v, want [0 3.01 +Inf]", got)
+	}
+}
+
+func TestSweepData_InsertionLossStats(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 2e6, 3e6, 4e6},
+		S21:         []complex128{1, 0.1, complex(0, 0.01), 0.001},
+	}
+	mean, maxDB, minDB, flat, ok := d.InsertionLossStats(2e6, 3e6)
+	if !ok {
+		t.Fatal("expected ok for a populated window")
+	}
+	if math.Abs(mean-30) > 1e-9 || math.Abs(maxDB-40) > 1e-9 || math.Abs(minDB-20) > 1e-9 || math.Abs(flat-20) > 1e-9 {
+		t.Errorf("InsertionLossStats = %v, %v, %v, %v; want 30, 40, 20, 20", mean, maxDB, minDB, flat)
+	}
+
+	if _, _, _, _, ok := d.InsertionLossStats(5e6, 6e6); ok {
+		t.Error("expected ok=false for an empty window")
+	}
+	d.S21 = nil
+	if _, _, _, _, ok := d.InsertionLossStats(1e6, 4e6); ok {
+		t.Error("expected ok=false without S21")
+	}
+}
+
+func TestSweepData_VSWR(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 2e6, 3e6},
+		S11:         []complex128{0.5, complex(0, -0.2), 1.1},
+	}
+	v := d.VSWR()
+	if len(v) != 3 || v[0] != 3 || math.Abs(v[1]-1.5) > 1e-12 || !math.IsInf(v[2], 1) {
+		t.Errorf("VSWR() = %v, want [3 1.5 +Inf]", v)
+	}
+
+	freq, vswr := d.MinVSWR()
+	if freq != 2e6 || math.Abs(vswr-1.5) > 1e-12 {
+		t.Errorf("MinVSWR() = %g, %g; want 2e6, 1.5", freq, vswr)
+	}
+	if _, vswr := (SweepData{}).MinVSWR(); !math.IsInf(vswr, 1) {
+		t.Errorf("MinVSWR() on empty sweep = %g, want +Inf", vswr)
+	}
+}
+
+func TestSweepData_ReturnLossDB(t *testing.T) {
+	d := SweepData{S11: []complex128{0, complex(0, 0.1), -1}}
+	rl := d.ReturnLossDB()
+	if !math.IsInf(rl[0], 1) || math.Abs(rl[1]-20) > 1e-12 || rl[2] != 0 {
+		t.Errorf("ReturnLossDB() = %v, want [+Inf 20 0]", rl)
+	}
+	mag := d.ReflectionMagnitude()
+	if mag[0] != 0 || math.Abs(mag[1]-0.1) > 1e-12 || mag[2] != 1 {
+		t.Errorf("ReflectionMagnitude() = %v, want [0 0.1 1]", mag)
+	}
+}
+
+func TestSweepData_Impedance(t *testing.T) {
+	d := SweepData{S11: []complex128{0, complex(0, 1), 1}}
+	z := d.Impedance(0)
+	if z[0] != 50 || cmplx.Abs(z[1]-complex(0, 50)) > 1e-9 || z[2] != complex(maxImpedance, 0) {
+		t.Errorf("Impedance(0) = %v, want [50 50i 1e12]", z)
+	}
+	if z := d.Impedance(75); z[0] != 75 {
+		t.Errorf("Impedance(75)[0] = %v, want 75", z[0])
+	}
+}
+
+func TestSweepData_Resonance(t *testing.T) {
+	// Return loss 20 dB at 10 MHz, 17 dB at 9.9 and 10.1 MHz: the 3 dB
+	// edges land exactly on those points, so Q = 10 MHz / 200 kHz = 50
+	g20, g17 := complex(0.1, 0), complex(math.Pow(10, -17.0/20), 0)
+	d := SweepData{
+		Frequencies: []float64{9.8e6, 9.9e6, 10e6, 10.1e6, 10.2e6},
+		S11:         []complex128{0.9, g17, g20, g17, 0.9},
+	}
+	freq, q, err := d.Resonance()
+	if err != nil {
+		t.Fatalf("Resonance failed: %v", err)
+	}
+	if freq != 10e6 || math.Abs(q-50) > 1e-6 {
+		t.Errorf("Resonance() = %g, %g; want 10e6, 50", freq, q)
+	}
+
+	// Dip at the sweep edge
+	d.S11 = []complex128{g20, g17, 0.5, 0.9, 0.9}
+	if _, _, err := d.Resonance(); err == nil {
+		t.Error("Expected error for a dip at the sweep edge")
+	}
+	if _, _, err := (SweepData{Frequencies: []float64{1, 2}, S11: []complex128{0, 0}}).Resonance(); err == nil {
+		t.Error("Expected error for too few points")
+	}
+}
@@ -0,0 +1,75 @@
+package nanovna
+
+import (
+	"context"
+	"math"
+)
+
+// MonitorBand sweeps cfg repeatedly until ctx is cancelled and emits a sweep
+// only when its S11 (and S21, when both sweeps have it) magnitude deviates
+// from the last emitted sweep by more than changeThresholdDB at any point.
+// Comparing against the last emitted sweep rather than the previous one lets
+// slow drift accumulate until it crosses the threshold. The first sweep is
+// always emitted.
+//
+// Both channels are closed when monitoring stops. Cancelling ctx also
+// abandons a sweep in progress. A configuration or sweep error is sent on
+// the error channel and ends monitoring; errors caused by the cancellation
+// itself are not sent.
+func (d *Device) MonitorBand(ctx context.Context, cfg SweepConfig, changeThresholdDB float64) (<-chan SweepData, <-chan error) {
+	out := make(chan SweepData)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(out)
+
+		if err := d.SetSweepConfigModeContext(ctx, cfg.StartHz, cfg.StopHz, cfg.Points, cfg.Mode); err != nil {
+			if ctx.Err() == nil {
+				errs <- err
+			}
+			return
+		}
+
+		var last SweepData
+		emitted := false
+		for ctx.Err() == nil {
+			data, err := d.RunSweepContext(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					errs <- err
+				}
+				return
+			}
+			if emitted && maxDeviationDB(last, data) <= changeThresholdDB {
+				continue
+			}
+			select {
+			case out <- data:
+				last, emitted = data, true
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// maxDeviationDB returns the largest magnitude difference in dB between two
+// sweeps, or +Inf when their frequency grids differ.
+func maxDeviationDB(a, b SweepData) float64 {
+	if !sameGrid(a.Frequencies, b.Frequencies, gridToleranceHz) || len(a.S11) != len(b.S11) {
+		return math.Inf(1)
+	}
+	maxDev := 0.0
+	for i := range a.S11 {
+		maxDev = math.Max(maxDev, math.Abs(magnitudeDB(a.S11[i])-magnitudeDB(b.S11[i])))
+	}
+	if len(a.S21) == len(a.S11) && len(b.S21) == len(b.S11) {
+		for i := range a.S21 {
+			maxDev = math.Max(maxDev, math.Abs(magnitudeDB(a.S21[i])-magnitudeDB(b.S21[i])))
+		}
+	}
+	return maxDev
+}
@@ -0,0 +1,45 @@
+package nanovna
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestMaxDeviationDB(t *testing.T) {
+	a := SweepData{Frequencies: []float64{1e6, 2e6}, S11: []complex128{0.1, 0.1}}
+	b := SweepData{Frequencies: []float64{1e6, 2e6}, S11: []complex128{0.1, 0.2}}
+	if got := maxDeviationDB(a, b); math.Abs(got-20*math.Log10(2)) > 1e-9 {
+		t.Errorf("maxDeviationDB = %v, want %v", got, 20*math.Log10(2))
+	}
+	c := SweepData{Frequencies: []float64{1e6, 3e6}, S11: []complex128{0.1, 0.1}}
+	if !math.IsInf(maxDeviationDB(a, c), 1) {
+		t.Error("different grids should give +Inf deviation")
+	}
+}
+
+func TestDevice_MonitorBand_EmitsFirstSweep(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 2000000 2": shellResponse("sweep 1000000 2000000 2"),
+		"frequencies":             shellResponse("frequencies", "1000000", "2000000"),
+		"data 0":                  shellResponse("data 0", "0.1 0.2", "0.3 0.4"),
+	}}
+	dev, _ := Open("COM1", port)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out, errs := dev.MonitorBand(ctx, SweepConfig{StartHz: 1000000, StopHz: 2000000, Points: 2}, 1)
+	data, ok := <-out
+	if !ok {
+		t.Fatalf("monitor stopped early: %v", <-errs)
+	}
+	if len(data.S11) != 2 {
+		t.Errorf("got %d S11 points, want 2", len(data.S11))
+	}
+	cancel()
+	for range out {
+		t.Error("unchanged sweeps should not be emitted")
+	}
+	if err, ok := <-errs; ok {
+		t.Errorf("cancellation should not be reported, got %v", err)
+	}
+}
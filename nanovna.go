@@ -3,9 +3,12 @@
 package nanovna
 
 import (
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
-	"os"
+	"io"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +16,15 @@ import (
 	"github.com/tarm/serial"
 )
 
+// ErrUnsupported is returned when the connected hardware or firmware does not
+// provide the requested feature.
+var ErrUnsupported = errors.New("operation not supported by this device")
+
+// ErrUnsupportedCommand is returned when the firmware answers a command with
+// nothing but its "?" error marker, meaning the shell does not know the
+// command. It wraps ErrUnsupported, so errors.Is matches either.
+var ErrUnsupportedCommand = fmt.Errorf("command not recognized by firmware: %w", ErrUnsupported)
+
 // HardwareVariant represents different NanoVNA hardware versions.
 type HardwareVariant int
 
@@ -54,12 +66,21 @@ func (hv HardwareVariant) String() string {
 
 // HardwareInfo contains hardware-specific information and capabilities.
 type HardwareInfo struct {
-	Variant        HardwareVariant
-	FrequencyRange FrequencyRange
-	MaxSweepPoints int
-	SupportedPorts []string // S11, S21, S12, S22
-	CommandSet     CommandSet
-	Capabilities   HardwareCapabilities
+	Variant          HardwareVariant
+	FrequencyRange   FrequencyRange
+	MaxSweepPoints   int
+	SupportedPorts   []string // S11, S21, S12, S22
+	CommandSet       CommandSet
+	Capabilities     HardwareCapabilities
+	Screen           ScreenSize // LCD size in pixels, as returned by CaptureScreen
+	MaxBrightness    int        // Top of the firmware's backlight range; 0 when it cannot be dimmed
+	CalibrationSlots int        // On-board save areas for CalibrationSave/CalibrationLoad, numbered from 0
+}
+
+// ScreenSize is the size of a device's LCD in pixels.
+type ScreenSize struct {
+	Width  int
+	Height int
 }
 
 // FrequencyRange defines the frequency range for a hardware variant.
@@ -70,24 +91,28 @@ type FrequencyRange struct {
 
 // CommandSet defines the command set for different hardware variants.
 type CommandSet struct {
-	SweepCommand    string
-	FreqCommand     string
-	DataCommand     string
-	InfoCommand     string
-	VersionCommand  string
-	CalibrationSave string
-	CalibrationLoad string
-	PromptPattern   string
+	SweepCommand      string
+	FreqCommand       string
+	DataCommand       string
+	InfoCommand       string
+	VersionCommand    string
+	CalibrationSave   string
+	CalibrationLoad   string
+	PromptPattern     string
+	BrightnessCommand string // Empty when the backlight is not adjustable
 }
 
 // HardwareCapabilities defines what each hardware variant can do.
 type HardwareCapabilities struct {
-	HasS21           bool
-	HasTimeDomain    bool
-	HasCalibration   bool
-	HasMultiplePorts bool
-	HasGenerator     bool
-	HasSpectrumMode  bool
+	HasS21              bool
+	HasTimeDomain       bool
+	HasCalibration      bool
+	HasMultiplePorts    bool
+	HasGenerator        bool
+	HasSpectrumMode     bool
+	HasBinaryScan       bool // Firmware answers scan_bin with packed binary samples
+	HasBattery          bool // Firmware reports the battery voltage via vbat
+	HasBandwidthControl bool // Firmware sets the IF bandwidth via bandwidth
 }
 
 // getHardwareInfo returns hardware information for a given variant
@@ -97,10 +122,11 @@ func getHardwareInfo(variant HardwareVariant) HardwareInfo {
 	switch variant {
 	case VariantV1:
 		return HardwareInfo{
-			Variant:        VariantV1,
-			FrequencyRange: FrequencyRange{MinHz: 50000, MaxHz: 900000000}, // 50kHz - 900MHz
-			MaxSweepPoints: 101,
-			SupportedPorts: []string{"S11", "S21"},
+			Variant:          VariantV1,
+			FrequencyRange:   FrequencyRange{MinHz: 50000, MaxHz: 900000000}, // 50kHz - 900MHz
+			MaxSweepPoints:   101,
+			CalibrationSlots: 5,
+			SupportedPorts:   []string{"S11", "S21"},
 			CommandSet: CommandSet{
 				SweepCommand:    "sweep %d %d %d",
 				FreqCommand:     "frequencies",
@@ -112,45 +138,57 @@ func getHardwareInfo(variant HardwareVariant) HardwareInfo {
 				PromptPattern:   "ch>",
 			},
 			Capabilities: HardwareCapabilities{
-				HasS21:           true,
-				HasTimeDomain:    false,
-				HasCalibration:   true,
-				HasMultiplePorts: false,
-				HasGenerator:     false,
-				HasSpectrumMode:  false,
+				HasS21:              true,
+				HasTimeDomain:       false,
+				HasCalibration:      true,
+				HasMultiplePorts:    false,
+				HasGenerator:        false,
+				HasSpectrumMode:     false,
+				HasBinaryScan:       false,
+				HasBattery:          true,
+				HasBandwidthControl: false,
 			},
+			Screen: ScreenSize{Width: 320, Height: 240},
 		}
 	case VariantVH:
 		return HardwareInfo{
-			Variant:        VariantVH,
-			FrequencyRange: FrequencyRange{MinHz: 50000, MaxHz: 1500000000}, // 50kHz - 1.5GHz
-			MaxSweepPoints: 201,
-			SupportedPorts: []string{"S11", "S21"},
+			Variant:          VariantVH,
+			FrequencyRange:   FrequencyRange{MinHz: 50000, MaxHz: 1500000000}, // 50kHz - 1.5GHz
+			MaxSweepPoints:   201,
+			CalibrationSlots: 7,
+			SupportedPorts:   []string{"S11", "S21"},
 			CommandSet: CommandSet{
-				SweepCommand:    "sweep %d %d %d",
-				FreqCommand:     "frequencies",
-				DataCommand:     "data %d",
-				InfoCommand:     "info",
-				VersionCommand:  "version",
-				CalibrationSave: "save %d",
-				CalibrationLoad: "recall %d",
-				PromptPattern:   "ch>",
+				SweepCommand:      "sweep %d %d %d",
+				FreqCommand:       "frequencies",
+				DataCommand:       "data %d",
+				InfoCommand:       "info",
+				VersionCommand:    "version",
+				CalibrationSave:   "save %d",
+				CalibrationLoad:   "recall %d",
+				PromptPattern:     "ch>",
+				BrightnessCommand: "brightness %d",
 			},
 			Capabilities: HardwareCapabilities{
-				HasS21:           true,
-				HasTimeDomain:    true,
-				HasCalibration:   true,
-				HasMultiplePorts: false,
-				HasGenerator:     true,
-				HasSpectrumMode:  false,
+				HasS21:              true,
+				HasTimeDomain:       true,
+				HasCalibration:      true,
+				HasMultiplePorts:    false,
+				HasGenerator:        true,
+				HasSpectrumMode:     false,
+				HasBinaryScan:       false,
+				HasBattery:          true,
+				HasBandwidthControl: true,
 			},
+			Screen:        ScreenSize{Width: 320, Height: 240},
+			MaxBrightness: 100,
 		}
 	case VariantV2:
 		return HardwareInfo{
-			Variant:        VariantV2,
-			FrequencyRange: FrequencyRange{MinHz: 50000, MaxHz: 3000000000}, // 50kHz - 3GHz
-			MaxSweepPoints: 4000,
-			SupportedPorts: []string{"S11", "S21"},
+			Variant:          VariantV2,
+			FrequencyRange:   FrequencyRange{MinHz: 50000, MaxHz: 3000000000}, // 50kHz - 3GHz
+			MaxSweepPoints:   4000,
+			CalibrationSlots: 5,
+			SupportedPorts:   []string{"S11", "S21"},
 			CommandSet: CommandSet{
 				SweepCommand:    "sweep %d %d %d",
 				FreqCommand:     "freq",
@@ -162,20 +200,25 @@ func getHardwareInfo(variant HardwareVariant) HardwareInfo {
 				PromptPattern:   "2>",
 			},
 			Capabilities: HardwareCapabilities{
-				HasS21:           true,
-				HasTimeDomain:    true,
-				HasCalibration:   true,
-				HasMultiplePorts: false,
-				HasGenerator:     true,
-				HasSpectrumMode:  true,
+				HasS21:              true,
+				HasTimeDomain:       true,
+				HasCalibration:      true,
+				HasMultiplePorts:    false,
+				HasGenerator:        true,
+				HasSpectrumMode:     true,
+				HasBinaryScan:       true,
+				HasBattery:          false,
+				HasBandwidthControl: false,
 			},
+			Screen: ScreenSize{Width: 320, Height: 240},
 		}
 	case VariantV2Plus:
 		return HardwareInfo{
-			Variant:        VariantV2Plus,
-			FrequencyRange: FrequencyRange{MinHz: 50000, MaxHz: 6000000000}, // 50kHz - 6GHz
-			MaxSweepPoints: 4000,
-			SupportedPorts: []string{"S11", "S21"},
+			Variant:          VariantV2Plus,
+			FrequencyRange:   FrequencyRange{MinHz: 50000, MaxHz: 6000000000}, // 50kHz - 6GHz
+			MaxSweepPoints:   4000,
+			CalibrationSlots: 5,
+			SupportedPorts:   []string{"S11", "S21"},
 			CommandSet: CommandSet{
 				SweepCommand:    "sweep %d %d %d",
 				FreqCommand:     "freq",
@@ -187,20 +230,57 @@ func getHardwareInfo(variant HardwareVariant) HardwareInfo {
 				PromptPattern:   "2>",
 			},
 			Capabilities: HardwareCapabilities{
-				HasS21:           true,
-				HasTimeDomain:    true,
-				HasCalibration:   true,
-				HasMultiplePorts: false,
-				HasGenerator:     true,
-				HasSpectrumMode:  true,
+				HasS21:              true,
+				HasTimeDomain:       true,
+				HasCalibration:      true,
+				HasMultiplePorts:    false,
+				HasGenerator:        true,
+				HasSpectrumMode:     true,
+				HasBinaryScan:       true,
+				HasBattery:          false,
+				HasBandwidthControl: false,
 			},
+			Screen: ScreenSize{Width: 320, Height: 240},
 		}
 	case VariantV2Plus4:
 		return HardwareInfo{
-			Variant:        VariantV2Plus4,
-			FrequencyRange: FrequencyRange{MinHz: 50000, MaxHz: 6000000000}, // 50kHz - 6GHz
-			MaxSweepPoints: 4000,
-			SupportedPorts: []string{"S11", "S21", "S12", "S22"},
+			Variant:          VariantV2Plus4,
+			FrequencyRange:   FrequencyRange{MinHz: 50000, MaxHz: 6000000000}, // 50kHz - 6GHz
+			MaxSweepPoints:   4000,
+			CalibrationSlots: 5,
+			SupportedPorts:   []string{"S11", "S21", "S12", "S22"},
+			CommandSet: CommandSet{
+				SweepCommand:      "sweep %d %d %d",
+				FreqCommand:       "freq",
+				DataCommand:       "data %d",
+				InfoCommand:       "info",
+				VersionCommand:    "version",
+				CalibrationSave:   "save %d",
+				CalibrationLoad:   "recall %d",
+				PromptPattern:     "2>",
+				BrightnessCommand: "brightness %d",
+			},
+			Capabilities: HardwareCapabilities{
+				HasS21:              true,
+				HasTimeDomain:       true,
+				HasCalibration:      true,
+				HasMultiplePorts:    true,
+				HasGenerator:        true,
+				HasSpectrumMode:     true,
+				HasBinaryScan:       true,
+				HasBattery:          false,
+				HasBandwidthControl: false,
+			},
+			Screen:        ScreenSize{Width: 480, Height: 320},
+			MaxBrightness: 255,
+		}
+	case VariantSAA2:
+		return HardwareInfo{
+			Variant:          VariantSAA2,
+			FrequencyRange:   FrequencyRange{MinHz: 50000, MaxHz: 3000000000}, // 50kHz - 3GHz
+			MaxSweepPoints:   4000,
+			CalibrationSlots: 5,
+			SupportedPorts:   []string{"S11", "S21"},
 			CommandSet: CommandSet{
 				SweepCommand:    "sweep %d %d %d",
 				FreqCommand:     "freq",
@@ -212,20 +292,25 @@ func getHardwareInfo(variant HardwareVariant) HardwareInfo {
 				PromptPattern:   "2>",
 			},
 			Capabilities: HardwareCapabilities{
-				HasS21:           true,
-				HasTimeDomain:    true,
-				HasCalibration:   true,
-				HasMultiplePorts: true,
-				HasGenerator:     true,
-				HasSpectrumMode:  true,
+				HasS21:              true,
+				HasTimeDomain:       true,
+				HasCalibration:      true,
+				HasMultiplePorts:    false,
+				HasGenerator:        true,
+				HasSpectrumMode:     true,
+				HasBinaryScan:       true,
+				HasBattery:          false,
+				HasBandwidthControl: false,
 			},
+			Screen: ScreenSize{Width: 320, Height: 240},
 		}
 	case VariantTinysa:
 		return HardwareInfo{
-			Variant:        VariantTinysa,
-			FrequencyRange: FrequencyRange{MinHz: 100000, MaxHz: 960000000}, // 100kHz - 960MHz
-			MaxSweepPoints: 500,
-			SupportedPorts: []string{"S11"},
+			Variant:          VariantTinysa,
+			FrequencyRange:   FrequencyRange{MinHz: 100000, MaxHz: 960000000}, // 100kHz - 960MHz
+			MaxSweepPoints:   500,
+			CalibrationSlots: 5,
+			SupportedPorts:   []string{"S11"},
 			CommandSet: CommandSet{
 				SweepCommand:    "sweep %d %d %d",
 				FreqCommand:     "frequencies",
@@ -237,21 +322,56 @@ func getHardwareInfo(variant HardwareVariant) HardwareInfo {
 				PromptPattern:   "ch>",
 			},
 			Capabilities: HardwareCapabilities{
-				HasS21:           false,
-				HasTimeDomain:    false,
-				HasCalibration:   true,
-				HasMultiplePorts: false,
-				HasGenerator:     true,
-				HasSpectrumMode:  true,
+				HasS21:              false,
+				HasTimeDomain:       false,
+				HasCalibration:      true,
+				HasMultiplePorts:    false,
+				HasGenerator:        true,
+				HasSpectrumMode:     true,
+				HasBinaryScan:       false,
+				HasBattery:          true,
+				HasBandwidthControl: false,
 			},
+			Screen: ScreenSize{Width: 320, Height: 240},
+		}
+	case VariantLiteVNA:
+		return HardwareInfo{
+			Variant:          VariantLiteVNA,
+			FrequencyRange:   FrequencyRange{MinHz: 50000, MaxHz: 6300000000}, // 50kHz - 6.3GHz
+			MaxSweepPoints:   4000,
+			CalibrationSlots: 5,
+			SupportedPorts:   []string{"S11", "S21"},
+			CommandSet: CommandSet{
+				SweepCommand:    "sweep %d %d %d",
+				FreqCommand:     "frequencies",
+				DataCommand:     "data %d",
+				InfoCommand:     "info",
+				VersionCommand:  "version",
+				CalibrationSave: "save %d",
+				CalibrationLoad: "recall %d",
+				PromptPattern:   "ch>",
+			},
+			Capabilities: HardwareCapabilities{
+				HasS21:              true,
+				HasTimeDomain:       true,
+				HasCalibration:      true,
+				HasMultiplePorts:    false,
+				HasGenerator:        true,
+				HasSpectrumMode:     false,
+				HasBinaryScan:       true,
+				HasBattery:          true,
+				HasBandwidthControl: true,
+			},
+			Screen: ScreenSize{Width: 480, Height: 320},
 		}
 	default:
 		// Default/unknown hardware - use conservative settings
 		return HardwareInfo{
-			Variant:        VariantUnknown,
-			FrequencyRange: FrequencyRange{MinHz: 50000, MaxHz: 900000000},
-			MaxSweepPoints: 101,
-			SupportedPorts: []string{"S11"},
+			Variant:          VariantUnknown,
+			FrequencyRange:   FrequencyRange{MinHz: 50000, MaxHz: 900000000},
+			MaxSweepPoints:   101,
+			CalibrationSlots: 5,
+			SupportedPorts:   []string{"S11"},
 			CommandSet: CommandSet{
 				SweepCommand:    "sweep %d %d %d",
 				FreqCommand:     "frequencies",
@@ -263,15 +383,114 @@ func getHardwareInfo(variant HardwareVariant) HardwareInfo {
 				PromptPattern:   "ch>",
 			},
 			Capabilities: HardwareCapabilities{
-				HasS21:           false,
-				HasTimeDomain:    false,
-				HasCalibration:   true,
-				HasMultiplePorts: false,
-				HasGenerator:     false,
-				HasSpectrumMode:  false,
+				HasS21:              false,
+				HasTimeDomain:       false,
+				HasCalibration:      true,
+				HasMultiplePorts:    false,
+				HasGenerator:        false,
+				HasSpectrumMode:     false,
+				HasBinaryScan:       false,
+				HasBattery:          false,
+				HasBandwidthControl: false,
 			},
+			Screen: ScreenSize{Width: 320, Height: 240},
+		}
+	}
+}
+
+// Validate checks the hardware profile for internal consistency: S11 is
+// supported, the S21 and multi-port capability flags agree with
+// SupportedPorts, the frequency range and point limit are sane, and each
+// command format string has the number of %d verbs its callers supply.
+func (hi HardwareInfo) Validate() error {
+	has := func(port string) bool {
+		for _, p := range hi.SupportedPorts {
+			if p == port {
+				return true
+			}
+		}
+		return false
+	}
+	if !has("S11") {
+		return fmt.Errorf("%s: S11 missing from supported ports", hi.Variant)
+	}
+	if hi.Capabilities.HasS21 != has("S21") {
+		return fmt.Errorf("%s: HasS21 is %v but S21 supported is %v", hi.Variant, hi.Capabilities.HasS21, has("S21"))
+	}
+	if multi := has("S12") && has("S22"); hi.Capabilities.HasMultiplePorts != multi {
+		return fmt.Errorf("%s: HasMultiplePorts is %v but S12/S22 supported is %v",
+			hi.Variant, hi.Capabilities.HasMultiplePorts, multi)
+	}
+	if hi.FrequencyRange.MinHz <= 0 || hi.FrequencyRange.MinHz >= hi.FrequencyRange.MaxHz {
+		return fmt.Errorf("%s: invalid frequency range %g-%g Hz",
+			hi.Variant, hi.FrequencyRange.MinHz, hi.FrequencyRange.MaxHz)
+	}
+	if hi.MaxSweepPoints < 1 {
+		return fmt.Errorf("%s: invalid max sweep points %d", hi.Variant, hi.MaxSweepPoints)
+	}
+	if hi.CalibrationSlots < 1 {
+		return fmt.Errorf("%s: invalid calibration slot count %d", hi.Variant, hi.CalibrationSlots)
+	}
+	if hi.Screen.Width <= 0 || hi.Screen.Height <= 0 {
+		return fmt.Errorf("%s: invalid screen size %dx%d", hi.Variant, hi.Screen.Width, hi.Screen.Height)
+	}
+
+	cs := hi.CommandSet
+	formats := []struct {
+		name   string
+		format string
+		verbs  int
+	}{
+		{"SweepCommand", cs.SweepCommand, 3},
+		{"FreqCommand", cs.FreqCommand, 0},
+		{"DataCommand", cs.DataCommand, 1},
+		{"InfoCommand", cs.InfoCommand, 0},
+		{"VersionCommand", cs.VersionCommand, 0},
+		{"CalibrationSave", cs.CalibrationSave, 1},
+		{"CalibrationLoad", cs.CalibrationLoad, 1},
+	}
+	for _, f := range formats {
+		if f.format == "" {
+			return fmt.Errorf("%s: %s is empty", hi.Variant, f.name)
+		}
+		if n, ok := countIntVerbs(f.format); !ok || n != f.verbs {
+			return fmt.Errorf("%s: %s %q needs exactly %d %%d verbs", hi.Variant, f.name, f.format, f.verbs)
+		}
+	}
+	if cs.PromptPattern == "" {
+		return fmt.Errorf("%s: PromptPattern is empty", hi.Variant)
+	}
+	if cs.BrightnessCommand != "" {
+		if n, ok := countIntVerbs(cs.BrightnessCommand); !ok || n != 1 {
+			return fmt.Errorf("%s: BrightnessCommand %q needs exactly 1 %%d verb", hi.Variant, cs.BrightnessCommand)
+		}
+		if hi.MaxBrightness <= 0 {
+			return fmt.Errorf("%s: BrightnessCommand set but MaxBrightness is %d", hi.Variant, hi.MaxBrightness)
+		}
+	}
+	return nil
+}
+
+// countIntVerbs counts the %d verbs in format; ok is false if it contains
+// any other verb.
+func countIntVerbs(format string) (n int, ok bool) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 >= len(format) {
+			return n, false
+		}
+		i++
+		switch format[i] {
+		case '%':
+		case 'd':
+			n++
+		default:
+			return n, false
 		}
 	}
+	return n, true
 }
 
 // PortConfig holds serial port configuration details for debugging.
@@ -284,33 +503,90 @@ type PortConfig struct {
 	StopBits    serial.StopBits
 }
 
-// SerialPort is the interface for serial port operations (exported for debug wrapping).
-type SerialPort interface {
-	Write([]byte) (int, error)
-	Read([]byte) (int, error)
-	Close() error
-}
-
 // Device represents a connection to a NanoVNA device.
+//
+// Port exchanges are serialized: every command and its response are
+// exchanged under an internal lock, so goroutines sharing a Device cannot
+// interleave bytes on the port. The Device's own state (sweep
+// configuration, detected variant, calibration, retry and dwell settings,
+// cached channel layout) is not locked, so methods that change it, such as
+// SetSweepConfig, must not run concurrently with other methods; route
+// shared access through a MeasureQueue or a single goroutine. Operations
+// built from several commands, such as a sweep reading frequencies and then
+// data, may also have other goroutines' commands run between their steps.
 type Device struct {
 	Port         string
-	portHandle   SerialPort
-	config       *PortConfig     // Store configuration for debugging
-	version      string          // Store detected version string (v1, vh, v2, etc.)
-	variant      HardwareVariant // Store hardware variant enum
-	hardwareInfo HardwareInfo    // Store hardware capabilities and info
+	portHandle   Transport
+	ioLock       fairLock                  // Serializes command/response exchanges on portHandle
+	config       *PortConfig               // Store configuration for debugging
+	reopen       func() (Transport, error) // Opens a fresh transport for Reconnect; nil for custom transports
+	version      string                    // Store detected version string (v1, vh, v2, etc.)
+	variant      HardwareVariant           // Store hardware variant enum
+	hardwareInfo HardwareInfo              // Store hardware capabilities and info
+
+	byteOrder         binary.ByteOrder     // Byte order for binary protocol decoding
+	sweepConfig       SweepConfig          // Last configuration applied by SetSweepConfig
+	portZ0            float64              // Device port Z0 once known, 0 otherwise
+	terminator        string               // Command line terminator, "" means "\r"
+	detectRetries     int                  // Extra DetectVersion attempts after a failure
+	retry             RetryConfig          // Retry policy for failed shell commands
+	channelCount      int                  // Cached DataChannelCount result, 0 until probed
+	interleavedData   bool                 // Data rows carry their frequency ("freq re im")
+	dwellTime         time.Duration        // Per-point settling delay, 0 when disabled
+	dwellMode         DwellMode            // How dwellTime is enforced
+	sortByFrequency   bool                 // Sort RunSweep results by ascending frequency
+	alignSweepOrder   bool                 // Correct data order reported as ErrSweepOrder
+	noZeroPadS21      bool                 // Leave S21 nil instead of zero-filling it
+	strictParsing     bool                 // Abort streams on the first malformed row
+	diagnosticHandler func(error)          // Receives non-fatal parse diagnostics
+	logger            func(string, []byte) // Receives raw TX/RX traffic when set
 }
 
-// SetPortHandle allows replacing the underlying serial port (for debug wrapping)
-func (d *Device) SetPortHandle(sp SerialPort) {
+// SetPortHandle allows replacing the underlying transport (for debug wrapping)
+func (d *Device) SetPortHandle(sp Transport) {
+	d.ioLock.lock()
+	defer d.ioLock.unlock()
 	d.portHandle = sp
 }
 
-// GetPortHandle returns the underlying serial port (for debug wrapping).
-func (d *Device) GetPortHandle() SerialPort {
+// GetPortHandle returns the underlying transport (for debug wrapping).
+func (d *Device) GetPortHandle() Transport {
+	d.ioLock.lock()
+	defer d.ioLock.unlock()
 	return d.portHandle
 }
 
+// SetLogger installs fn to receive every chunk written to ("TX") and read
+// from ("RX") the port, for capturing the exact protocol exchange in bug
+// reports. fn gets its own copy of the bytes and is called with the port
+// lock held, so it must not issue device commands. A nil fn disables
+// logging.
+func (d *Device) SetLogger(fn func(direction string, data []byte)) {
+	d.ioLock.lock()
+	defer d.ioLock.unlock()
+	d.logger = fn
+}
+
+// portWrite writes p to the transport, passing it to the logger if one is
+// set. The caller holds ioLock.
+func (d *Device) portWrite(p []byte) (int, error) {
+	n, err := d.portHandle.Write(p)
+	if d.logger != nil && n > 0 {
+		d.logger("TX", append([]byte(nil), p[:n]...))
+	}
+	return n, err
+}
+
+// portRead reads from the transport into p, passing what arrived to the
+// logger if one is set. The caller holds ioLock.
+func (d *Device) portRead(p []byte) (int, error) {
+	n, err := d.portHandle.Read(p)
+	if d.logger != nil && n > 0 {
+		d.logger("RX", append([]byte(nil), p[:n]...))
+	}
+	return n, err
+}
+
 // GetPortConfig returns the port configuration details (for debugging).
 func (d *Device) GetPortConfig() *PortConfig {
 	return d.config
@@ -366,6 +642,30 @@ func (d *Device) IsPortSupported(port string) bool {
 	return false
 }
 
+// PortConnected reports whether something appears to be attached to port.
+// No known firmware senses port termination directly, so for S11 this runs a
+// sweep with the current configuration and applies SweepData.LikelyOpenPort.
+// Other ports return ErrUnsupported.
+func (d *Device) PortConnected(port string) (bool, error) {
+	return d.PortConnectedContext(context.Background(), port)
+}
+
+// PortConnectedContext is like PortConnected but stops waiting for the
+// device once ctx is done.
+func (d *Device) PortConnectedContext(ctx context.Context, port string) (bool, error) {
+	if !d.IsPortSupported(port) {
+		return false, fmt.Errorf("port %s is not supported by %s", port, d.variant.String())
+	}
+	if port != "S11" {
+		return false, ErrUnsupported
+	}
+	data, err := d.RunSweepContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !data.LikelyOpenPort(), nil
+}
+
 // DeviceInfo contains information about the NanoVNA device.
 type DeviceInfo struct {
 	Model     string
@@ -373,60 +673,133 @@ type DeviceInfo struct {
 	SerialNum string
 }
 
+// DefaultZ0 is the reference impedance assumed when none is specified.
+const DefaultZ0 = 50.0
+
 // SweepData holds measurement data from a sweep.
 type SweepData struct {
 	Frequencies []float64
 	S11         []complex128
 	S21         []complex128
+	S12         []complex128 // Reverse transmission; nil unless loaded from a file
+	S22         []complex128 // Output reflection; nil unless loaded from a file
+	// Z0 is the reference impedance the S-parameters are normalized to, in
+	// ohms. Zero means DefaultZ0. RunSweep fills it with the device port Z0
+	// when that has been read or set through GetPortZ0/SetPortZ0.
+	Z0 float64
+}
+
+// referenceZ0 returns the sweep's reference impedance, defaulting to DefaultZ0.
+func (d SweepData) referenceZ0() float64 {
+	if d.Z0 > 0 {
+		return d.Z0
+	}
+	return DefaultZ0
 }
 
-// CalibrationData holds calibration coefficients and metadata.
+// CalibrationData holds error-correction terms, one value per point of the
+// frequency grid they were measured on. The three one-port terms are always
+// present in a valid calibration; the through terms are optional and empty
+// for a one-port calibration.
 type CalibrationData struct {
-	// TODO: define calibration fields
+	Frequencies []float64
+
+	// One-port error terms
+	Directivity        []complex128 // e00 (firmware ED)
+	SourceMatch        []complex128 // e11 (firmware ES)
+	ReflectionTracking []complex128 // e10·e01 (firmware ER)
+
+	// Through error terms
+	TransmissionTracking []complex128 // e10·e32 (firmware stores 1/ET)
+	Isolation            []complex128 // e30 (firmware EX)
+	// Standards the terms were solved from; nil when not known, as for
+	// terms read from the device
+	Standards []CalStandard
 }
 
-// ListDevices lists available NanoVNA serial ports (Windows only, stub).
-func ListDevices() ([]string, error) {
-	var ports []string
-	for i := 1; i <= 20; i++ {
-		portName := fmt.Sprintf("COM%d", i)
-		f, err := os.Open("//./" + portName)
-		if err == nil {
-			ports = append(ports, portName)
-			f.Close()
+// Valid reports whether the calibration has a frequency grid, all one-port
+// terms with one value per frequency, and through terms that are either
+// absent or also one per frequency.
+func (c CalibrationData) Valid() bool {
+	n := len(c.Frequencies)
+	if n == 0 || len(c.Directivity) != n || len(c.SourceMatch) != n || len(c.ReflectionTracking) != n {
+		return false
+	}
+	for _, t := range [][]complex128{c.TransmissionTracking, c.Isolation} {
+		if len(t) != 0 && len(t) != n {
+			return false
 		}
 	}
+	return true
+}
+
+// HasThrough reports whether the calibration includes through terms.
+func (c CalibrationData) HasThrough() bool {
+	return len(c.TransmissionTracking) > 0 && len(c.TransmissionTracking) == len(c.Frequencies)
+}
+
+// ListDevices lists available serial ports that may host a NanoVNA: COM1
+// to COM20 on Windows, /dev/ttyACM* and /dev/ttyUSB* on Linux, and
+// /dev/cu.usbmodem* and /dev/cu.usbserial* on macOS.
+func ListDevices() ([]string, error) {
+	ports, err := listSerialPorts()
+	if err != nil {
+		return nil, err
+	}
 	if len(ports) == 0 {
 		return nil, errors.New("no serial ports found")
 	}
 	return ports, nil
 }
 
+// autoDetectBauds are the baud rates AutoDetect tries on each port, in
+// order. USB CDC firmware ignores the rate, but some V2 devices behind a
+// USB-UART bridge only answer at their configured speed.
+var autoDetectBauds = []int{9600, 115200, 921600}
+
 // AutoDetect attempts to find and connect to a NanoVNA device automatically.
+// Ports with a known NanoVNA USB ID are probed; where USB IDs cannot be
+// read, or none match, every serial port is probed. Each port is tried at
+// 9600, 115200 and 921600 baud, and the first combination that passes
+// DetectVersion is returned.
 func AutoDetect() (*Device, error) {
-	ports, err := ListDevices()
+	ports, err := ListDevicesFiltered(nil)
+	if err != nil || len(ports) == 0 {
+		ports, err = ListDevices()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to list serial ports: %v", err)
 	}
 
+	open := func(port string, cfg OpenConfig) (*Device, error) {
+		return OpenWithConfig(port, cfg)
+	}
 	for _, port := range ports {
-		device, err := Open(port)
-		if err != nil {
-			continue // Try next port
+		if device, err := detectOnPort(port, autoDetectBauds, open); err == nil {
+			return device, nil
 		}
+	}
 
-		// Try to detect the version/hardware
-		_, err = device.DetectVersion()
+	return nil, errors.New("no NanoVNA devices found on any serial port")
+}
+
+// detectOnPort opens port at each baud rate in turn with open and returns
+// the first device that passes DetectVersion, closing failed attempts.
+func detectOnPort(port string, bauds []int, open func(string, OpenConfig) (*Device, error)) (*Device, error) {
+	err := errors.New("no baud rates to try")
+	for _, baud := range bauds {
+		var device *Device
+		device, err = open(port, OpenConfig{Baud: baud})
 		if err != nil {
+			continue
+		}
+		if _, err = device.DetectVersion(); err != nil {
 			device.Close()
-			continue // Try next port
+			continue
 		}
-
-		// Successfully detected a NanoVNA
 		return device, nil
 	}
-
-	return nil, errors.New("no NanoVNA devices found on any serial port")
+	return nil, fmt.Errorf("%s: %v", port, err)
 }
 
 // OpenWithVariant opens a device and forces a specific hardware variant
@@ -460,38 +833,79 @@ func OpenWithVariant(port string, variant HardwareVariant) (*Device, error) {
 	return device, nil
 }
 
-// Open connects to a NanoVNA on the specified serial port. Optionally accepts a custom SerialPort for debug/testing.
-func Open(port string, custom ...SerialPort) (*Device, error) {
+// OpenConfig holds the serial parameters used to open a port. Zero fields
+// take the defaults of Open: 9600 baud, a 5-second read timeout and 8N1.
+type OpenConfig struct {
+	Baud        int
+	ReadTimeout time.Duration
+	Size        byte
+	Parity      serial.Parity
+	StopBits    serial.StopBits
+}
+
+// withDefaults returns cfg with zero fields replaced by the Open defaults.
+func (cfg OpenConfig) withDefaults() OpenConfig {
+	if cfg.Baud == 0 {
+		cfg.Baud = 9600
+	}
+	if cfg.ReadTimeout == 0 {
+		// A read timeout prevents hanging on a silent device
+		cfg.ReadTimeout = time.Second * 5
+	}
+	if cfg.Size == 0 {
+		cfg.Size = 8
+	}
+	if cfg.Parity == 0 {
+		cfg.Parity = serial.ParityNone
+	}
+	if cfg.StopBits == 0 {
+		cfg.StopBits = serial.Stop1
+	}
+	return cfg
+}
+
+// Open connects to a NanoVNA on the specified serial port with the default
+// serial parameters. Optionally accepts a custom Transport, used instead of
+// opening the port, for other transports, debugging and testing.
+func Open(port string, custom ...Transport) (*Device, error) {
+	return OpenWithConfig(port, OpenConfig{}, custom...)
+}
+
+// OpenWithConfig is like Open but uses the serial parameters in cfg. The
+// values actually used, after defaults are applied, are recorded in the
+// PortConfig reported by GetPortConfig and GetPortDetails, also when a
+// custom Transport is supplied.
+func OpenWithConfig(port string, cfg OpenConfig, custom ...Transport) (*Device, error) {
+	cfg = cfg.withDefaults()
 	device := &Device{Port: port}
 
 	if len(custom) > 0 && custom[0] != nil {
 		device.portHandle = custom[0]
 	} else {
-		// Set a 5-second read timeout to prevent hanging
-		c := &serial.Config{
+		sc := &serial.Config{
 			Name:        port,
-			Baud:        9600,
-			ReadTimeout: time.Second * 5,
-			Size:        8,
-			Parity:      serial.ParityNone,
-			StopBits:    serial.Stop1,
+			Baud:        cfg.Baud,
+			ReadTimeout: cfg.ReadTimeout,
+			Size:        cfg.Size,
+			Parity:      cfg.Parity,
+			StopBits:    cfg.StopBits,
 		}
-		s, err := serial.OpenPort(c)
+		s, err := openSerial(sc)
 		if err != nil {
 			return nil, err
 		}
-
-		// Store configuration for debugging
-		device.config = &PortConfig{
-			Name:        c.Name,
-			Baud:        c.Baud,
-			ReadTimeout: c.ReadTimeout,
-			Size:        c.Size,
-			Parity:      c.Parity,
-			StopBits:    c.StopBits,
-		}
-
 		device.portHandle = s
+		device.reopen = func() (Transport, error) { return openSerial(sc) }
+	}
+
+	// Store configuration for debugging
+	device.config = &PortConfig{
+		Name:        port,
+		Baud:        cfg.Baud,
+		ReadTimeout: cfg.ReadTimeout,
+		Size:        cfg.Size,
+		Parity:      cfg.Parity,
+		StopBits:    cfg.StopBits,
 	}
 
 	// Initialize with unknown hardware until detection
@@ -501,8 +915,36 @@ func Open(port string, custom ...SerialPort) (*Device, error) {
 	return device, nil
 }
 
-// SetSweepConfig configures sweep parameters (start, stop, points).
+// SweepConfig describes a frequency sweep.
+type SweepConfig struct {
+	StartHz int
+	StopHz  int
+	Points  int
+	Mode    SweepMode // Point spacing; see SetSweepConfigMode
+}
+
+// SetSweepConfig configures sweep parameters (start, stop, points). The
+// range must be ascending (startHz < stopHz) with at least 2 points.
 func (d *Device) SetSweepConfig(startHz, stopHz int, points int) error {
+	return d.SetSweepConfigContext(context.Background(), startHz, stopHz, points)
+}
+
+// SetSweepConfigContext is like SetSweepConfig but stops waiting for the
+// device once ctx is done.
+func (d *Device) SetSweepConfigContext(ctx context.Context, startHz, stopHz int, points int) error {
+	if startHz >= stopHz {
+		return fmt.Errorf("start frequency %d Hz must be below stop frequency %d Hz", startHz, stopHz)
+	}
+	if points < 2 {
+		return fmt.Errorf("sweep needs at least 2 points, got %d", points)
+	}
+	return d.configureSweep(ctx, startHz, stopHz, points)
+}
+
+// configureSweep checks the sweep against the hardware limits and sends it
+// to the device. Unlike SetSweepConfig it accepts single-frequency sweeps
+// (startHz == stopHz, 1 point), which the point-by-point measurements use.
+func (d *Device) configureSweep(ctx context.Context, startHz, stopHz int, points int) error {
 	// Validate frequency range against hardware capabilities
 	if float64(startHz) < d.hardwareInfo.FrequencyRange.MinHz {
 		return fmt.Errorf("start frequency %d Hz is below minimum %g Hz for %s",
@@ -524,130 +966,204 @@ func (d *Device) SetSweepConfig(startHz, stopHz int, points int) error {
 	switch d.variant {
 	case VariantV2, VariantV2Plus, VariantV2Plus4:
 		// V2 variants might need a different command sequence
-		_, err := d.sendCommand(cmd)
+		_, err := d.sendCommandContext(ctx, cmd)
 		if err != nil {
 			// Try alternative V2 command format
 			altCmd := fmt.Sprintf("sweep start %d", startHz)
-			if _, err2 := d.sendCommand(altCmd); err2 != nil {
+			if _, err2 := d.sendCommandContext(ctx, altCmd); err2 != nil {
 				altCmd = fmt.Sprintf("sweep stop %d", stopHz)
-				if _, err3 := d.sendCommand(altCmd); err3 != nil {
+				if _, err3 := d.sendCommandContext(ctx, altCmd); err3 != nil {
 					altCmd = fmt.Sprintf("sweep points %d", points)
-					if _, err4 := d.sendCommand(altCmd); err4 != nil {
+					if _, err4 := d.sendCommandContext(ctx, altCmd); err4 != nil {
 						return fmt.Errorf("failed to set sweep config with any V2 command format: %v", err)
 					}
 				}
 			}
 		}
-		return nil
 	default:
 		// Standard command for V1, VH, and other variants
-		_, err := d.sendCommand(cmd)
+		_, err := d.sendCommandContext(ctx, cmd)
 		if err != nil {
 			// Fallback to individual commands
-			if _, err2 := d.sendCommand(fmt.Sprintf("start %d", startHz)); err2 != nil {
-				if _, err3 := d.sendCommand(fmt.Sprintf("stop %d", stopHz)); err3 != nil {
-					if _, err4 := d.sendCommand(fmt.Sprintf("points %d", points)); err4 != nil {
+			if _, err2 := d.sendCommandContext(ctx, fmt.Sprintf("start %d", startHz)); err2 != nil {
+				if _, err3 := d.sendCommandContext(ctx, fmt.Sprintf("stop %d", stopHz)); err3 != nil {
+					if _, err4 := d.sendCommandContext(ctx, fmt.Sprintf("points %d", points)); err4 != nil {
 						return fmt.Errorf("failed to set sweep config: %v", err)
 					}
 				}
 			}
 		}
-		return nil
 	}
+
+	d.sweepConfig = SweepConfig{StartHz: startHz, StopHz: stopHz, Points: points}
+	return nil
+}
+
+// defaultSweepPoints is the point count ApplyDefaultSweep uses, reduced to
+// the hardware maximum where that is lower.
+const defaultSweepPoints = 101
+
+// ApplyDefaultSweep configures a sweep over the full frequency range of the
+// detected hardware variant at a modest point count, so that RunSweep returns
+// meaningful data straight after connecting. Call it after DetectVersion (or
+// AutoDetect) so the variant's limits are known.
+func (d *Device) ApplyDefaultSweep() error {
+	return d.ApplyDefaultSweepContext(context.Background())
+}
+
+// ApplyDefaultSweepContext is like ApplyDefaultSweep but stops waiting for
+// the device once ctx is done.
+func (d *Device) ApplyDefaultSweepContext(ctx context.Context) error {
+	points := defaultSweepPoints
+	if limit := d.hardwareInfo.MaxSweepPoints; limit > 0 && limit < points {
+		points = limit
+	}
+	fr := d.hardwareInfo.FrequencyRange
+	return d.SetSweepConfigContext(ctx, int(fr.MinHz), int(fr.MaxHz), points)
+}
+
+// SetSweepByResolution configures a sweep from startHz to stopHz with the
+// point count needed for a step of at most resolutionHz. The count is
+// clamped to MaxSweepPoints, in which case the step is coarser than asked;
+// SweepConfig.Resolution on GetSweepConfig reports the achieved step.
+func (d *Device) SetSweepByResolution(startHz, stopHz int, resolutionHz int) error {
+	return d.SetSweepByResolutionContext(context.Background(), startHz, stopHz, resolutionHz)
+}
+
+// SetSweepByResolutionContext is like SetSweepByResolution but stops waiting
+// for the device once ctx is done.
+func (d *Device) SetSweepByResolutionContext(ctx context.Context, startHz, stopHz int, resolutionHz int) error {
+	if resolutionHz <= 0 {
+		return fmt.Errorf("resolution must be positive, got %d Hz", resolutionHz)
+	}
+	if stopHz <= startHz {
+		return fmt.Errorf("stop frequency %d Hz must be above start frequency %d Hz", stopHz, startHz)
+	}
+	span := stopHz - startHz
+	points := (span+resolutionHz-1)/resolutionHz + 1
+	if limit := d.hardwareInfo.MaxSweepPoints; limit > 0 && points > limit {
+		points = limit
+	}
+	return d.SetSweepConfigContext(ctx, startHz, stopHz, points)
+}
+
+// Resolution returns the frequency step of a linear sweep in Hz, or 0 for a
+// sweep of fewer than two points.
+func (c SweepConfig) Resolution() float64 {
+	if c.Points < 2 {
+		return 0
+	}
+	return float64(c.StopHz-c.StartHz) / float64(c.Points-1)
+}
+
+// GetSweepConfig returns the sweep configuration last applied with
+// SetSweepConfig, and false if none has been applied on this Device.
+func (d *Device) GetSweepConfig() (SweepConfig, bool) {
+	return d.sweepConfig, d.sweepConfig.Points > 0
 }
 
 // RunSweep triggers a sweep and returns measurement data.
 // Uses hardware-specific commands and handles different port configurations.
+// With a host-side dwell time set (see SetDwellTime) or a logarithmic sweep
+// (see SetSweepConfigMode) the configured sweep is measured point by point
+// instead.
 func (d *Device) RunSweep() (SweepData, error) {
-	var data SweepData
+	return d.RunSweepContext(context.Background())
+}
 
-	// Step 1: Get frequencies using hardware-specific command
-	freqCmd := d.hardwareInfo.CommandSet.FreqCommand
-	freqResp, err := d.sendCommand(freqCmd)
-	if err != nil {
-		return SweepData{}, fmt.Errorf("failed to get frequencies: %v", err)
+// RunSweepContext is like RunSweep but stops when ctx is done, both between
+// the frequency, S11 and S21 queries and while waiting for a response, and
+// then returns ctx.Err().
+func (d *Device) RunSweepContext(ctx context.Context) (SweepData, error) {
+	return d.runSweep(ctx, nil)
+}
+
+// runSweep implements RunSweepContext, reporting to progress when non-nil.
+func (d *Device) runSweep(ctx context.Context, progress *sweepProgress) (SweepData, error) {
+	var data SweepData
+	var err error
+	if d.dwellMode == DwellHost || d.sweepConfig.Mode != SweepLinear {
+		data, err = d.runPointSweep(ctx, progress)
+	} else {
+		data, err = d.readSweep(ctx, progress)
+	}
+	if err != nil && ctx.Err() != nil {
+		return SweepData{}, ctx.Err()
 	}
+	return data, err
+}
 
-	// Parse frequencies
-	freqLines := strings.Split(freqResp, "\n")
-	for _, line := range freqLines {
-		line = strings.TrimSpace(line)
-		if line == "" || line == freqCmd ||
-			strings.Contains(line, d.hardwareInfo.CommandSet.PromptPattern) ||
-			strings.Contains(line, "?") {
-			continue
-		}
+// readSweep reads the frequencies and data channels of the current sweep,
+// counting the received rows against progress when it is non-nil.
+func (d *Device) readSweep(ctx context.Context, progress *sweepProgress) (SweepData, error) {
+	var data SweepData
+	progress.expect(d.sweepRowCount())
 
-		freq, err := strconv.ParseFloat(line, 64)
-		if err == nil {
-			data.Frequencies = append(data.Frequencies, freq)
+	// Step 1: Get frequencies using hardware-specific command, unless the
+	// data command is known to carry them
+	if !d.interleavedData {
+		freqLines, err := d.readSweepLines(ctx, progress, d.hardwareInfo.CommandSet.FreqCommand)
+		if err != nil {
+			return SweepData{}, fmt.Errorf("failed to get frequencies: %v", err)
+		}
+		for _, line := range freqLines {
+			freq, err := strconv.ParseFloat(line, 64)
+			if err == nil {
+				data.Frequencies = append(data.Frequencies, freq)
+			}
 		}
 	}
 
 	// Step 2: Get S11 data (always available)
-	s11Cmd := fmt.Sprintf(d.hardwareInfo.CommandSet.DataCommand, 0)
-	s11Resp, err := d.sendCommand(s11Cmd)
+	if err := ctx.Err(); err != nil {
+		return SweepData{}, err
+	}
+	s11Lines, err := d.readSweepLines(ctx, progress, fmt.Sprintf(d.hardwareInfo.CommandSet.DataCommand, 0))
 	if err != nil {
 		return SweepData{}, fmt.Errorf("failed to get S11 data: %v", err)
 	}
-
-	// Parse S11 data (complex numbers: real imaginary)
-	s11Lines := strings.Split(s11Resp, "\n")
+	var inlineFreqs []float64
 	for _, line := range s11Lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "data") ||
-			strings.Contains(line, d.hardwareInfo.CommandSet.PromptPattern) ||
-			strings.Contains(line, "?") {
+		freq, v, err := parseDataLine(line)
+		if err != nil {
 			continue
 		}
-
-		parts := strings.Fields(line)
-		if len(parts) >= 2 {
-			real, err1 := strconv.ParseFloat(parts[0], 64)
-			imag, err2 := strconv.ParseFloat(parts[1], 64)
-			if err1 == nil && err2 == nil {
-				data.S11 = append(data.S11, complex(real, imag))
-			}
+		data.S11 = append(data.S11, v)
+		if !math.IsNaN(freq) {
+			inlineFreqs = append(inlineFreqs, freq)
 		}
 	}
 
+	// Firmware printing "freq real imag" rows makes the separate
+	// frequencies query unnecessary on later sweeps
+	switch {
+	case len(inlineFreqs) > 0 && len(inlineFreqs) == len(data.S11):
+		data.Frequencies = inlineFreqs
+		d.interleavedData = true
+	case d.interleavedData:
+		// The format changed; fall back to the frequencies query
+		d.interleavedData = false
+		return d.readSweep(ctx, progress)
+	}
+
 	// Step 3: Get S21 data if supported
+	if err := ctx.Err(); err != nil {
+		return SweepData{}, err
+	}
 	if d.hardwareInfo.Capabilities.HasS21 && d.IsPortSupported("S21") {
-		s21Cmd := fmt.Sprintf(d.hardwareInfo.CommandSet.DataCommand, 1)
-		s21Resp, err := d.sendCommand(s21Cmd)
-		if err != nil {
-			// S21 might not be available, create dummy data
-			for range data.S11 {
-				data.S21 = append(data.S21, complex(0, 0))
-			}
-		} else {
-			// Parse S21 data
-			s21Lines := strings.Split(s21Resp, "\n")
+		s21Lines, err := d.readSweepLines(ctx, progress, fmt.Sprintf(d.hardwareInfo.CommandSet.DataCommand, 1))
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return SweepData{}, ctxErr
+		}
+		if err == nil {
 			for _, line := range s21Lines {
-				line = strings.TrimSpace(line)
-				if line == "" || strings.HasPrefix(line, "data") ||
-					strings.Contains(line, d.hardwareInfo.CommandSet.PromptPattern) ||
-					strings.Contains(line, "?") {
-					continue
-				}
-
-				parts := strings.Fields(line)
-				if len(parts) >= 2 {
-					real, err1 := strconv.ParseFloat(parts[0], 64)
-					imag, err2 := strconv.ParseFloat(parts[1], 64)
-					if err1 == nil && err2 == nil {
-						data.S21 = append(data.S21, complex(real, imag))
-					}
+				if v, err := parseComplexLine(line); err == nil {
+					data.S21 = append(data.S21, v)
 				}
 			}
 		}
 	}
 
-	// Ensure S21 has same length as S11 (pad with zeros if needed)
-	for len(data.S21) < len(data.S11) {
-		data.S21 = append(data.S21, complex(0, 0))
-	}
-
 	// Validate we got some data
 	if len(data.Frequencies) == 0 || len(data.S11) == 0 {
 		return SweepData{}, fmt.Errorf("no valid measurement data received")
@@ -661,20 +1177,256 @@ func (d *Device) RunSweep() (SweepData, error) {
 
 	data.Frequencies = data.Frequencies[:minLen]
 	data.S11 = data.S11[:minLen]
-	if len(data.S21) >= minLen {
+	data.Z0 = d.portZ0
+	switch {
+	case len(data.S21) >= minLen:
 		data.S21 = data.S21[:minLen]
-	} else {
+	case d.noZeroPadS21:
+		// Incomplete or missing S21 is reported as absent
+		data.S21 = nil
+	default:
 		// Pad S21 with zeros if needed
 		for len(data.S21) < minLen {
 			data.S21 = append(data.S21, complex(0, 0))
 		}
 	}
 
+	data = d.checkSweepOrder(data)
+	if d.sortByFrequency {
+		data = data.SortByFrequency()
+	}
+
 	return data, nil
 }
 
+// ErrSweepOrder is reported through the diagnostic handler when the order of
+// the data rows appears inconsistent with the reported frequencies.
+var ErrSweepOrder = errors.New("sweep data order inconsistent with frequencies")
+
+// SetAlignSweepOrder controls whether RunSweep corrects the inconsistencies
+// it reports as ErrSweepOrder: data from a sweep configured with start above
+// stop is reversed to line up with ascending frequencies, and a sweep whose
+// frequencies are not monotonic is sorted with SortByFrequency. Disabled by
+// default, in which case the data is returned as read.
+func (d *Device) SetAlignSweepOrder(enable bool) {
+	d.alignSweepOrder = enable
+}
+
+// checkSweepOrder reports, and optionally corrects, data rows that do not
+// line up with the frequency list. Rows that carry their own frequency are
+// aligned by construction and are not checked.
+func (d *Device) checkSweepOrder(data SweepData) SweepData {
+	n := len(data.Frequencies)
+	if n < 2 || d.interleavedData {
+		return data
+	}
+	cfg := d.sweepConfig
+
+	switch {
+	case !isMonotonic(data.Frequencies):
+		d.diagnose(fmt.Errorf("%w: frequencies are not monotonic", ErrSweepOrder))
+		if d.alignSweepOrder {
+			data = data.SortByFrequency()
+		}
+	case cfg.Points > 0 && cfg.StartHz > cfg.StopHz && data.Frequencies[0] < data.Frequencies[n-1]:
+		// The device swept downwards, so the data rows run from high to
+		// low frequency while the frequency list ascends
+		d.diagnose(fmt.Errorf("%w: sweep configured from %d down to %d Hz but frequencies ascend",
+			ErrSweepOrder, cfg.StartHz, cfg.StopHz))
+		if d.alignSweepOrder {
+			data.S11 = reversedComplex(data.S11)
+			data.S21 = reversedComplex(data.S21)
+			data.S12 = reversedComplex(data.S12)
+			data.S22 = reversedComplex(data.S22)
+		}
+	}
+	return data
+}
+
+// isMonotonic reports whether v is entirely non-decreasing or non-increasing.
+func isMonotonic(v []float64) bool {
+	up, down := true, true
+	for i := 1; i < len(v); i++ {
+		up = up && v[i] >= v[i-1]
+		down = down && v[i] <= v[i-1]
+	}
+	return up || down
+}
+
+// reversedComplex returns a reversed copy of v, or nil when v is nil.
+func reversedComplex(v []complex128) []complex128 {
+	if v == nil {
+		return nil
+	}
+	out := make([]complex128, len(v))
+	for i, c := range v {
+		out[len(v)-1-i] = c
+	}
+	return out
+}
+
+// SetSortByFrequency makes RunSweep return points in strictly ascending
+// frequency order (see SweepData.SortByFrequency), guarding against firmware
+// that reports a descending sweep. Disabled by default.
+func (d *Device) SetSortByFrequency(enable bool) {
+	d.sortByFrequency = enable
+}
+
+// maxDataChannels bounds DataChannelCount probing; NanoVNA firmware exposes
+// channels 0-6 (S11, S21 and the five calibration arrays).
+const maxDataChannels = 7
+
+// DataChannelCount returns how many consecutive `data N` channels, starting
+// at 0, return valid measurement rows. The device is probed on first use and
+// the result cached until the hardware is re-detected.
+func (d *Device) DataChannelCount() (int, error) {
+	return d.DataChannelCountContext(context.Background())
+}
+
+// DataChannelCountContext is like DataChannelCount but stops waiting for the
+// device once ctx is done.
+func (d *Device) DataChannelCountContext(ctx context.Context) (int, error) {
+	if d.channelCount > 0 {
+		return d.channelCount, nil
+	}
+
+	count := 0
+	for ch := 0; ch < maxDataChannels; ch++ {
+		lines, err := d.readLinesContext(ctx, fmt.Sprintf(d.hardwareInfo.CommandSet.DataCommand, ch))
+		if err != nil || len(lines) == 0 {
+			break
+		}
+		if _, err := parseComplexLine(lines[0]); err != nil {
+			break
+		}
+		count++
+	}
+	if count == 0 {
+		return 0, errors.New("no data channels returned valid data")
+	}
+
+	d.channelCount = count
+	return count, nil
+}
+
+// readLines sends cmd and returns the payload lines of its response.
+func (d *Device) readLines(cmd string) ([]string, error) {
+	return d.readLinesContext(context.Background(), cmd)
+}
+
+// readLinesContext is readLines with cancellation through ctx.
+func (d *Device) readLinesContext(ctx context.Context, cmd string) ([]string, error) {
+	resp, err := d.sendCommandContext(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return d.responseLines(resp, cmd), nil
+}
+
+// responseLines splits a command response into trimmed payload lines,
+// dropping blank lines, the command echo, the prompt and firmware error markers.
+func (d *Device) responseLines(resp, cmd string) []string {
+	var lines []string
+	for _, line := range strings.Split(resp, "\n") {
+		line = strings.TrimSpace(line)
+		if d.payloadLine(line, cmd) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// payloadLine reports whether a trimmed response line to cmd carries data,
+// rather than being blank, the command echo, the prompt or an error marker.
+func (d *Device) payloadLine(line, cmd string) bool {
+	var echo string
+	if fields := strings.Fields(cmd); len(fields) > 0 {
+		echo = fields[0]
+	}
+	return !(line == "" || line == cmd || (echo != "" && strings.HasPrefix(line, echo)) ||
+		strings.Contains(line, d.hardwareInfo.CommandSet.PromptPattern) ||
+		strings.Contains(line, "?"))
+}
+
+// streamLinesContext sends cmd and passes each payload line to fn as soon as
+// it has been received, instead of after the whole response. Once fn returns
+// false the remaining lines are read and discarded so that the next command
+// starts cleanly. fn runs while the port is locked and must not call
+// methods on d.
+func (d *Device) streamLinesContext(ctx context.Context, cmd string, fn func(line string) bool) error {
+	var pending string
+	stopped := false
+	deliver := func(line string) {
+		line = strings.TrimSpace(line)
+		if !stopped && d.payloadLine(line, cmd) && !fn(line) {
+			stopped = true
+		}
+	}
+	_, err := d.exchangeContext(ctx, cmd, func(chunk string) {
+		pending += chunk
+		for {
+			i := strings.IndexByte(pending, '\n')
+			if i < 0 {
+				return
+			}
+			deliver(pending[:i])
+			pending = pending[i+1:]
+		}
+	})
+	if err != nil {
+		return err
+	}
+	deliver(pending) // A last line without a newline
+	return nil
+}
+
+// parseComplexLine parses a "real imaginary" or "freq real imaginary" data
+// row, discarding the frequency.
+func parseComplexLine(line string) (complex128, error) {
+	_, v, err := parseDataLine(line)
+	return v, err
+}
+
+// parseDataLine parses a data row. Rows with three numeric fields are
+// "freq real imaginary" triplets; freq is NaN for plain "real imaginary" rows.
+func parseDataLine(line string) (freq float64, v complex128, err error) {
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("expected 2 fields, got %d", len(parts))
+	}
+	nums := make([]float64, 0, 3)
+	for _, p := range parts {
+		if len(nums) == 3 {
+			break
+		}
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			break
+		}
+		nums = append(nums, f)
+	}
+	switch len(nums) {
+	case 3:
+		return nums[0], complex(nums[1], nums[2]), nil
+	case 2:
+		return math.NaN(), complex(nums[0], nums[1]), nil
+	}
+	_, err = strconv.ParseFloat(parts[len(nums)], 64)
+	return 0, 0, err
+}
+
+// SetZeroPadS21 controls how RunSweep reports missing S21 data. When pad is
+// true (the default) S21 is zero-filled to the length of S11 if the hardware
+// returned no or partial S21 data; when false S21 is left nil instead, so
+// "no S21" can be told apart from a measurement of zeros.
+func (d *Device) SetZeroPadS21(pad bool) {
+	d.noZeroPadS21 = !pad
+}
+
 // Close disconnects from the device.
 func (d *Device) Close() error {
+	d.ioLock.lock()
+	defer d.ioLock.unlock()
 	if d.portHandle != nil {
 		err := d.portHandle.Close()
 		d.portHandle = nil
@@ -683,62 +1435,319 @@ func (d *Device) Close() error {
 	return nil
 }
 
+// openSerial opens a serial port transport; tests substitute a scripted one.
+var openSerial = NewSerialTransport
+
+// Reconnect closes the current transport and reopens it the way it was
+// first opened, the same serial port path and parameters or the same
+// OpenTCP address, then re-runs DetectVersion to restore the variant and
+// hardware profile. It recovers a session after the device re-enumerates
+// under the same name, as Linux typically does with /dev/ttyACM0 after a
+// firmware reset. Devices opened on a custom Transport cannot be reopened;
+// Reconnect returns an error for them and leaves the transport open.
+func (d *Device) Reconnect() error {
+	return d.ReconnectContext(context.Background())
+}
+
+// ReconnectContext is like Reconnect but stops waiting for the device once
+// ctx is done.
+func (d *Device) ReconnectContext(ctx context.Context) error {
+	if d.reopen == nil {
+		return errors.New("device was opened on a custom transport and cannot be reopened")
+	}
+	d.Close() // The old handle is usually dead already
+
+	t, err := d.reopen()
+	if err != nil {
+		return fmt.Errorf("failed to reopen %s: %v", d.Port, err)
+	}
+	d.SetPortHandle(t)
+
+	// The firmware may have changed across the reset
+	d.channelCount = 0
+	d.interleavedData = false
+	if _, err := d.DetectVersionContext(ctx); err != nil {
+		return fmt.Errorf("failed to detect device after reopening %s: %v", d.Port, err)
+	}
+	return nil
+}
+
+// SetCommandTerminator sets the line terminator appended to every command.
+// The default is "\r"; some clones need "\r\n" or "\n" and otherwise act on
+// each command only when the next one arrives. When no terminator has been
+// set, DetectVersion picks the first of "\r", "\r\n" and "\n" that produces
+// a prompt.
+func (d *Device) SetCommandTerminator(s string) {
+	d.terminator = s
+}
+
+// commandTerminator returns the configured terminator or the "\r" default.
+func (d *Device) commandTerminator() string {
+	if d.terminator == "" {
+		return "\r"
+	}
+	return d.terminator
+}
+
 // sendCommand sends a command string to the NanoVNA and returns the response.
 // Uses proper protocol based on detected version.
 func (d *Device) sendCommand(cmd string) (string, error) {
+	return d.sendCommandContext(context.Background(), cmd)
+}
+
+// prompt returns the shell prompt of the detected variant, which marks the
+// end of a command response.
+func (d *Device) prompt() string {
+	if p := d.hardwareInfo.CommandSet.PromptPattern; p != "" {
+		return p
+	}
+	return "ch>"
+}
+
+// responseQuietPeriod is how long sendCommandContext waits for more data
+// after a transport reports an empty read before ending the response.
+const responseQuietPeriod = 500 * time.Millisecond
+
+// sendCommandContext is sendCommand with cancellation through ctx. It reads
+// until the prompt appears, the device goes quiet or ctx is done; a ctx
+// deadline also bounds blocking reads on transports implementing
+// ReadDeadliner. A response holding only the firmware's error marker fails
+// with ErrUnsupportedCommand.
+func (d *Device) sendCommandContext(ctx context.Context, cmd string) (string, error) {
+	return d.sendCommandChunks(ctx, cmd, nil)
+}
+
+// sendCommandChunks is sendCommandContext that also passes each chunk of the
+// response to onChunk as it arrives. A retried command delivers its chunks
+// again.
+func (d *Device) sendCommandChunks(ctx context.Context, cmd string, onChunk func(chunk string)) (string, error) {
+	return d.sendCommandAttempts(ctx, cmd, onChunk, d.retry.MaxAttempts)
+}
+
+// sendCommandOnce is sendCommandContext without retries, for commands that
+// must not reach the device twice, such as `save` or an arbitrary raw
+// command.
+func (d *Device) sendCommandOnce(ctx context.Context, cmd string) (string, error) {
+	return d.sendCommandAttempts(ctx, cmd, nil, 1)
+}
+
+// sendCommandAttempts exchanges cmd up to attempts times, waiting between
+// attempts as d.retry prescribes, and maps a bare error marker reply to
+// ErrUnsupportedCommand.
+func (d *Device) sendCommandAttempts(ctx context.Context, cmd string, onChunk func(chunk string), attempts int) (string, error) {
+	resp, err := d.exchangeContext(ctx, cmd, onChunk)
+	for attempt := 1; err != nil && attempt < attempts; attempt++ {
+		if ctx.Err() != nil || errors.Is(err, errDeviceNotOpen) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		case <-time.After(d.retry.delay(attempt)):
+		}
+		resp, err = d.exchangeContext(ctx, cmd, onChunk)
+	}
+	if err == nil && rejectedCommand(resp, cmd) && len(d.responseLines(resp, cmd)) == 0 {
+		return resp, ErrUnsupportedCommand
+	}
+	return resp, err
+}
+
+// errDeviceNotOpen is returned by commands issued without an open transport.
+var errDeviceNotOpen = errors.New("device not open")
+
+// RetryConfig is the policy for repeating a command whose exchange failed,
+// for example after a USB CDC hiccup on a flaky hub. The whole command is
+// sent again, so only the library's idempotent queries and settings are
+// retried; SendRawCommand, SaveCalibration, LoadCalibration, streamed
+// reads and raw transactions are sent once.
+type RetryConfig struct {
+	MaxAttempts int           // Total attempts per command; 0 or 1 disables retries
+	Backoff     time.Duration // Wait before the first retry, doubled for each further one
+	MaxBackoff  time.Duration // Upper bound on the wait, 0 for none
+}
+
+// delay returns the wait before retry attempt (1 for the first retry).
+func (rc RetryConfig) delay(attempt int) time.Duration {
+	wait := rc.Backoff
+	for i := 1; i < attempt && wait < 1<<62; i++ {
+		wait *= 2
+	}
+	if rc.MaxBackoff > 0 && wait > rc.MaxBackoff {
+		wait = rc.MaxBackoff
+	}
+	return wait
+}
+
+// SetRetryConfig sets the retry policy for shell commands. The default is a
+// single attempt, so a read or write error fails the command immediately.
+func (d *Device) SetRetryConfig(rc RetryConfig) {
+	d.retry = rc
+}
+
+// exchangeContext implements sendCommandContext, additionally passing each
+// chunk to onChunk, when non-nil, as soon as it is read.
+func (d *Device) exchangeContext(ctx context.Context, cmd string, onChunk func(chunk string)) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	d.ioLock.lock()
+	defer d.ioLock.unlock()
 	if d.portHandle == nil {
-		return "", errors.New("device not open")
+		return "", errDeviceNotOpen
 	}
 
 	// Clear any existing data first
 	buf := make([]byte, 1024)
-	d.portHandle.Read(buf) // drain buffer
+	d.portRead(buf) // drain buffer
 
 	// Send command with proper termination
-	cmdBytes := []byte(cmd + "\r")
-	_, err := d.portHandle.Write(cmdBytes)
+	cmdBytes := []byte(cmd + d.commandTerminator())
+	_, err := d.portWrite(cmdBytes)
 	if err != nil {
 		return "", fmt.Errorf("failed to write command: %v", err)
 	}
 
-	// Small delay after sending to let device process
-	time.Sleep(50 * time.Millisecond)
+	// Bound blocking reads by the context deadline where the transport allows it
+	if rd, ok := d.portHandle.(ReadDeadliner); ok {
+		if deadline, has := ctx.Deadline(); has {
+			rd.SetReadDeadline(deadline)
+			defer rd.SetReadDeadline(time.Time{})
+		}
+	}
 
-	// Read response with proper parsing for NanoVNA protocol
+	// Read until the prompt marks the end of the response
 	var response strings.Builder
-	maxAttempts := 10
-
-	for attempts := 0; attempts < maxAttempts; attempts++ {
-		n, err := d.portHandle.Read(buf)
-		if err != nil {
+	lastData := time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return response.String(), err
+		}
+		n, err := d.portRead(buf)
+		if n > 0 {
+			response.WriteString(string(buf[:n]))
+			lastData = time.Now()
+			if onChunk != nil {
+				onChunk(string(buf[:n]))
+			}
+			if strings.Contains(response.String(), d.prompt()) {
+				break
+			}
+		}
+		if (err == nil || err == io.EOF) && n == 0 {
+			// Transports without a read timeout report silence as an
+			// empty read; give the device a quiet period to continue
+			if time.Since(lastData) < responseQuietPeriod {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			err = errors.New("timeout")
+		}
+		if err != nil && err != io.EOF {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return response.String(), ctxErr
+			}
 			if strings.Contains(err.Error(), "timeout") && response.Len() > 0 {
-				break // We got some data, timeout is OK
+				break // The device went quiet without a prompt; keep what arrived
 			}
 			return response.String(), err
 		}
+	}
 
-		if n > 0 {
-			chunk := string(buf[:n])
-			response.WriteString(chunk)
+	return response.String(), nil
+}
 
-			// Check if we've received the command prompt indicating end of response
-			if strings.Contains(chunk, "ch>") {
-				break
-			}
+// SendRawCommand sends a shell command the library does not model, such as
+// `help` or `threshold`, and returns the response text as received: the
+// command echo and payload, with the trailing prompt stripped. Commands the
+// firmware does not know fail with ErrUnsupportedCommand.
+func (d *Device) SendRawCommand(cmd string) (string, error) {
+	return d.SendRawCommandContext(context.Background(), cmd)
+}
+
+// SendRawCommandContext is like SendRawCommand but stops waiting for the
+// device once ctx is done.
+func (d *Device) SendRawCommandContext(ctx context.Context, cmd string) (string, error) {
+	resp, err := d.sendCommandOnce(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	if i := strings.LastIndex(resp, d.prompt()); i >= 0 {
+		resp = resp[:i]
+	}
+	return resp, nil
+}
+
+// Transaction writes raw bytes to the device and then reads until readUntil
+// reports true for the bytes received so far, or until timeout elapses. It
+// is the low-level primitive beneath the text and binary protocols, for
+// commands the library does not model. A nil readUntil reads for the whole
+// timeout. On timeout the bytes received so far are returned with an error.
+func (d *Device) Transaction(write []byte, readUntil func([]byte) bool, timeout time.Duration) ([]byte, error) {
+	return d.transaction(false, write, readUntil, timeout)
+}
+
+// transaction implements Transaction. A priority transaction takes the port
+// lock ahead of queued normal commands, for brief interactive operations.
+func (d *Device) transaction(priority bool, write []byte, readUntil func([]byte) bool, timeout time.Duration) ([]byte, error) {
+	if priority {
+		d.ioLock.lockPriority()
+	} else {
+		d.ioLock.lock()
+	}
+	defer d.ioLock.unlock()
+	if d.portHandle == nil {
+		return nil, errors.New("device not open")
+	}
+
+	if len(write) > 0 {
+		if _, err := d.portWrite(write); err != nil {
+			return nil, fmt.Errorf("failed to write command: %v", err)
 		}
+	}
 
-		// Small delay between reads
-		time.Sleep(20 * time.Millisecond)
+	var resp []byte
+	buf := make([]byte, 1024)
+	deadline := time.Now().Add(timeout)
+	if rd, ok := d.portHandle.(ReadDeadliner); ok {
+		// Keep a blocking read from outlasting the transaction
+		rd.SetReadDeadline(deadline)
+		defer rd.SetReadDeadline(time.Time{})
+	}
+	for time.Now().Before(deadline) {
+		n, err := d.portRead(buf)
+		if n > 0 {
+			resp = append(resp, buf[:n]...)
+			if readUntil != nil && readUntil(resp) {
+				return resp, nil
+			}
+		}
+		if err != nil && !strings.Contains(err.Error(), "timeout") {
+			return resp, err
+		}
+		if n == 0 {
+			// Nothing available yet
+			time.Sleep(10 * time.Millisecond)
+		}
 	}
 
-	return response.String(), nil
+	if readUntil == nil {
+		return resp, nil
+	}
+	return resp, fmt.Errorf("transaction timed out after %v", timeout)
 }
 
 // GetInfo retrieves device information (model, firmware, serial number).
 func (d *Device) GetInfo() (DeviceInfo, error) {
+	return d.GetInfoContext(context.Background())
+}
+
+// GetInfoContext is like GetInfo but stops waiting for the device once ctx
+// is done.
+func (d *Device) GetInfoContext(ctx context.Context) (DeviceInfo, error) {
 	// Use hardware-specific info command
 	infoCmd := d.hardwareInfo.CommandSet.InfoCommand
-	resp, err := d.sendCommand(infoCmd)
+	resp, err := d.sendCommandContext(ctx, infoCmd)
 	if err != nil {
 		return DeviceInfo{}, err
 	}
@@ -805,36 +1814,72 @@ func (d *Device) GetInfo() (DeviceInfo, error) {
 	return info, nil
 }
 
-// DetectVersion detects the NanoVNA version by sending CR and analyzing the response
-func (d *Device) DetectVersion() (string, error) {
-	if d.portHandle == nil {
-		return "", errors.New("device not open")
-	}
-
-	// Clear any existing data
-	buf := make([]byte, 1024)
-	d.portHandle.Read(buf) // drain buffer
+// GetRawInfo returns the full response of the info command with the command
+// echo and prompt removed, one line per row, for fields GetInfo does not parse.
+func (d *Device) GetRawInfo() (string, error) {
+	return d.GetRawInfoContext(context.Background())
+}
 
-	// Send carriage return to detect version
-	_, err := d.portHandle.Write([]byte("\r"))
+// GetRawInfoContext is like GetRawInfo but stops waiting for the device once
+// ctx is done.
+func (d *Device) GetRawInfoContext(ctx context.Context) (string, error) {
+	lines, err := d.readLinesContext(ctx, d.hardwareInfo.CommandSet.InfoCommand)
 	if err != nil {
 		return "", err
 	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// detectRetryDelay is the pause between DetectVersion attempts, giving a
+// device that is still booting time to bring up its shell.
+const detectRetryDelay = 250 * time.Millisecond
 
-	// Read response with timeout
-	time.Sleep(50 * time.Millisecond)
-	n, err := d.portHandle.Read(buf)
+// SetDetectRetries sets how many times DetectVersion repeats the detection
+// sequence after an unrecognized or missing response before giving up. A
+// device that was plugged in moments ago may still be booting and answer the
+// first attempt with garbage. The default is 0 (a single attempt).
+func (d *Device) SetDetectRetries(n int) {
+	d.detectRetries = n
+}
+
+// DetectVersion detects the NanoVNA version by sending CR and analyzing the response.
+// Failed attempts are repeated as configured with SetDetectRetries.
+func (d *Device) DetectVersion() (string, error) {
+	return d.DetectVersionContext(context.Background())
+}
+
+// DetectVersionContext is like DetectVersion but stops waiting for the
+// device once ctx is done.
+func (d *Device) DetectVersionContext(ctx context.Context) (string, error) {
+	version, err := d.detectOnce(ctx)
+	for attempt := 0; err != nil && attempt < d.detectRetries; attempt++ {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(detectRetryDelay):
+		}
+		version, err = d.detectOnce(ctx)
+	}
+	return version, err
+}
+
+// detectOnce runs a single detection sequence.
+func (d *Device) detectOnce(ctx context.Context) (string, error) {
+	response, err := d.probePrompt(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	response := string(buf[:n])
-
 	// Try to get more info to distinguish between variants
-	info, _ := d.sendCommand("info")
-
-	// Detect hardware variant based on response patterns and info
-	if strings.HasPrefix(response, "ch> ") {
+	info, _ := d.sendCommandContext(ctx, "info")
+
+	// Detect hardware variant based on response patterns and info. Only the
+	// text just before the prompt matters, so noise left over from before
+	// the probe does not affect the result: V1-style firmware answers the
+	// bare terminator with the prompt alone, NanoVNA-H echoes a line break
+	// (and possibly "?") first.
+	beforePrompt, _, chPrompt := strings.Cut(response, "ch>")
+	if chPrompt && !strings.HasSuffix(beforePrompt, "\r\n") {
 		d.version = "v1"
 		d.variant = VariantV1
 		if strings.Contains(strings.ToLower(info), "tinysa") {
@@ -842,7 +1887,7 @@ func (d *Device) DetectVersion() (string, error) {
 		} else if strings.Contains(strings.ToLower(info), "litevna") {
 			d.variant = VariantLiteVNA
 		}
-	} else if strings.HasPrefix(response, "\r\nch> ") || strings.HasPrefix(response, "\r\n?\r\nch> ") {
+	} else if chPrompt {
 		d.version = "vh"
 		d.variant = VariantVH
 		// Check if it's actually a v1 with different prompt
@@ -867,6 +1912,8 @@ func (d *Device) DetectVersion() (string, error) {
 
 	// Get hardware info for detected variant
 	d.hardwareInfo = getHardwareInfo(d.variant)
+	d.channelCount = 0 // re-probe for the detected hardware
+	d.interleavedData = false
 
 	if d.variant == VariantUnknown {
 		return "unknown", fmt.Errorf("unrecognized response: %q", response)
@@ -875,27 +1922,246 @@ func (d *Device) DetectVersion() (string, error) {
 	return d.version, nil
 }
 
+// probePrompt writes a bare terminator and returns what the device answers,
+// trying the common terminators in turn unless one was set explicitly.
+func (d *Device) probePrompt(ctx context.Context) (string, error) {
+	d.ioLock.lock()
+	defer d.ioLock.unlock()
+	if d.portHandle == nil {
+		return "", errors.New("device not open")
+	}
+
+	// Clear any existing data
+	buf := make([]byte, 1024)
+	d.portRead(buf) // drain buffer
+
+	// Send a bare terminator to provoke a prompt. Unless the caller chose a
+	// terminator, try the common ones in turn and keep the first that works.
+	candidates := []string{d.commandTerminator()}
+	if d.terminator == "" {
+		candidates = []string{"\r", "\r\n", "\n"}
+	}
+
+	var response string
+	var readErr error
+	for _, term := range candidates {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if _, err := d.portWrite([]byte(term)); err != nil {
+			return "", err
+		}
+
+		got, err := d.readPrompt(ctx, buf)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		if got != "" {
+			response = got
+		} else if err != nil {
+			readErr = err
+		}
+		if hasShellPrompt(got) {
+			d.terminator = term
+			break
+		}
+	}
+	if response == "" && readErr != nil {
+		return "", readErr
+	}
+	return response, nil
+}
+
+// promptProbeTimeout bounds how long probePrompt waits for a prompt after
+// each terminator; a device still enumerating may answer in pieces.
+const promptProbeTimeout = 300 * time.Millisecond
+
+// hasShellPrompt reports whether s contains a ChibiOS ("ch>") or V2 ("2>")
+// shell prompt.
+func hasShellPrompt(s string) bool {
+	return strings.Contains(s, "ch>") || strings.Contains(s, "2>")
+}
+
+// readPrompt accumulates reads into one response until it contains a shell
+// prompt, promptProbeTimeout passes or ctx is done. The last read error is
+// returned only when nothing arrived. The caller holds ioLock.
+func (d *Device) readPrompt(ctx context.Context, buf []byte) (string, error) {
+	var response strings.Builder
+	var readErr error
+	deadline := time.Now().Add(promptProbeTimeout)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		n, err := d.portRead(buf)
+		if n > 0 {
+			response.Write(buf[:n])
+			if hasShellPrompt(response.String()) {
+				return response.String(), nil
+			}
+		}
+		if err != nil {
+			readErr = err
+		}
+		if n == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if response.Len() == 0 {
+		return "", readErr
+	}
+	return response.String(), nil
+}
+
+// EnterDFU reboots the device into its USB DFU bootloader so new firmware can
+// be flashed (for example with dfu-util). The device re-enumerates as a DFU
+// device and disappears from serial enumeration, so the port is closed and
+// the Device must not be used afterwards.
+func (d *Device) EnterDFU() error {
+	return d.EnterDFUContext(context.Background())
+}
+
+// EnterDFUContext is like EnterDFU but stops waiting for the device once ctx
+// is done.
+func (d *Device) EnterDFUContext(ctx context.Context) error {
+	// The firmware resets immediately and never answers with a prompt, so
+	// the command is written directly rather than through sendCommand.
+	cmd := "dfu"
+	switch d.variant {
+	case VariantV1, VariantVH, VariantTinysa, VariantLiteVNA:
+		cmd = "reset dfu"
+	}
+	if _, err := d.Transaction([]byte(cmd+d.commandTerminator()), nil, 0); err != nil {
+		return fmt.Errorf("failed to write dfu command: %v", err)
+	}
+
+	return d.Close()
+}
+
 // GetVersion returns the detected NanoVNA version.
 func (d *Device) GetVersion() string {
 	return d.version
 }
 
-// GetCalibration retrieves current calibration data.
+// calibrationChannelBase is the `data` channel holding the first error term
+// (ED); ES, ER, ET and EX follow in order.
+const calibrationChannelBase = 2
+
+// GetCalibration reads the device's active error terms from its
+// `data 2`-`data 6` channels (ED, ES, ER, ET, EX) on the current frequency
+// grid. The through terms are left empty when the firmware
+// does not provide them. V2-family devices, which do not expose their error
+// terms over the shell, return ErrUnsupported.
 func (d *Device) GetCalibration() (CalibrationData, error) {
-	return CalibrationData{}, nil
+	return d.GetCalibrationContext(context.Background())
+}
+
+// GetCalibrationContext is like GetCalibration but stops waiting for the
+// device once ctx is done.
+func (d *Device) GetCalibrationContext(ctx context.Context) (CalibrationData, error) {
+	switch d.variant {
+	case VariantV2, VariantV2Plus, VariantV2Plus4, VariantSAA2:
+		return CalibrationData{}, ErrUnsupported
+	}
+	if d.portHandle == nil {
+		return CalibrationData{}, errors.New("device not open")
+	}
+
+	var cal CalibrationData
+	terms := []*[]complex128{
+		&cal.Directivity, &cal.SourceMatch, &cal.ReflectionTracking,
+		&cal.TransmissionTracking, &cal.Isolation,
+	}
+	for i, term := range terms {
+		lines, err := d.readLinesContext(ctx, fmt.Sprintf(d.hardwareInfo.CommandSet.DataCommand, calibrationChannelBase+i))
+		if err != nil {
+			if i >= 3 && errors.Is(err, ErrUnsupported) {
+				break // one-port firmware
+			}
+			return CalibrationData{}, err
+		}
+		for _, line := range lines {
+			if v, err := parseComplexLine(line); err == nil {
+				*term = append(*term, v)
+			}
+		}
+	}
+
+	// The firmware stores the reciprocal of the transmission tracking term
+	for i, v := range cal.TransmissionTracking {
+		if v != 0 {
+			cal.TransmissionTracking[i] = 1 / v
+		}
+	}
+
+	freqLines, err := d.readLinesContext(ctx, d.hardwareInfo.CommandSet.FreqCommand)
+	if err != nil {
+		return CalibrationData{}, fmt.Errorf("failed to get frequencies: %v", err)
+	}
+	for _, line := range freqLines {
+		if f, err := strconv.ParseFloat(line, 64); err == nil {
+			cal.Frequencies = append(cal.Frequencies, f)
+		}
+	}
+	if !cal.Valid() {
+		return CalibrationData{}, fmt.Errorf("calibration terms do not match the %d-point frequency grid",
+			len(cal.Frequencies))
+	}
+	return cal, nil
 }
 
-// SetCalibration applies calibration data.
+// SetCalibration would upload cal as the device's active calibration, but
+// no known firmware has a shell command to write error terms, so it always
+// returns ErrUnsupported and leaves the device untouched. Correct sweeps on
+// the host with ApplyCalibration instead, or store the terms on the device
+// by calibrating there and calling SaveCalibration.
 func (d *Device) SetCalibration(cal CalibrationData) error {
-	return nil
+	return ErrUnsupported
 }
 
-// SaveCalibration saves calibration data to device memory.
+// SaveCalibration stores the device's active calibration and sweep settings
+// in on-board slot with CommandSet.CalibrationSave. The slot must be below
+// HardwareInfo.CalibrationSlots: 5 on stock NanoVNA firmware, 7 on the
+// NanoVNA-H profile, which covers H4-class firmware with more save areas.
 func (d *Device) SaveCalibration(slot int) error {
-	return nil
+	return d.SaveCalibrationContext(context.Background(), slot)
+}
+
+// SaveCalibrationContext is like SaveCalibration but stops waiting for the
+// device once ctx is done.
+func (d *Device) SaveCalibrationContext(ctx context.Context, slot int) error {
+	return d.calibrationSlotCommand(ctx, d.hardwareInfo.CommandSet.CalibrationSave, slot)
 }
 
-// LoadCalibration loads calibration data from device memory.
+// LoadCalibration makes the calibration stored in on-board slot active with
+// CommandSet.CalibrationLoad, subject to the same slot check as
+// SaveCalibration. The firmware also restores the sweep settings saved with
+// it, so call SetSweepConfig afterwards to sweep a different range.
 func (d *Device) LoadCalibration(slot int) error {
+	return d.LoadCalibrationContext(context.Background(), slot)
+}
+
+// LoadCalibrationContext is like LoadCalibration but stops waiting for the
+// device once ctx is done.
+func (d *Device) LoadCalibrationContext(ctx context.Context, slot int) error {
+	return d.calibrationSlotCommand(ctx, d.hardwareInfo.CommandSet.CalibrationLoad, slot)
+}
+
+// calibrationSlotCommand validates slot and sends format with it, turning
+// a usage or error message from the firmware into an error.
+func (d *Device) calibrationSlotCommand(ctx context.Context, format string, slot int) error {
+	count := d.hardwareInfo.CalibrationSlots
+	if slot < 0 || slot >= count {
+		return fmt.Errorf("calibration slot %d out of range 0-%d", slot, count-1)
+	}
+
+	cmd := fmt.Sprintf(format, slot)
+	resp, err := d.sendCommandOnce(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	for _, line := range d.responseLines(resp, cmd) {
+		lower := strings.ToLower(line)
+		if strings.HasPrefix(lower, "usage") || strings.Contains(lower, "error") || strings.Contains(lower, "fail") {
+			return fmt.Errorf("%s: %s", cmd, line)
+		}
+	}
 	return nil
 }
@@ -1,8 +1,15 @@
 package nanovna
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/tarm/serial"
 )
 
 // MockSerialPort implements the SerialPort interface for testing
@@ -32,6 +39,49 @@ func (m *MockSerialPort) Close() error {
 	return nil
 }
 
+// ScriptedSerialPort answers each written command with a canned response,
+// emulating the request/response behaviour of the firmware shell.
+type ScriptedSerialPort struct {
+	Responses map[string]string
+	Written   []string
+	Closed    bool
+	pending   []byte
+}
+
+func (s *ScriptedSerialPort) Write(p []byte) (int, error) {
+	cmd := strings.TrimRight(string(p), "\r\n")
+	s.Written = append(s.Written, cmd)
+	if resp, ok := s.Responses[cmd]; ok {
+		s.pending = append(s.pending, resp...)
+	}
+	return len(p), nil
+}
+
+func (s *ScriptedSerialPort) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 {
+		return 0, errors.New("timeout")
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+func (s *ScriptedSerialPort) Close() error {
+	s.Closed = true
+	return nil
+}
+
+// shellResponse formats a firmware reply: command echo, payload lines and prompt.
+func shellResponse(cmd string, lines ...string) string {
+	var b strings.Builder
+	b.WriteString(cmd + "\r\n")
+	for _, l := range lines {
+		b.WriteString(l + "\r\n")
+	}
+	b.WriteString("ch> ")
+	return b.String()
+}
+
 func TestDevice_IsPortSupported_TableDriven(t *testing.T) {
 	dev := &Device{
 		hardwareInfo: getHardwareInfo(VariantV2Plus4),
@@ -73,6 +123,26 @@ func TestDevice_GetPortConfigAndDetails(t *testing.T) {
 	}
 }
 
+func TestOpenWithConfig(t *testing.T) {
+	dev, err := OpenWithConfig("COM1", OpenConfig{Baud: 115200, ReadTimeout: time.Second}, &MockSerialPort{})
+	if err != nil {
+		t.Fatalf("OpenWithConfig failed: %v", err)
+	}
+	want := PortConfig{Name: "COM1", Baud: 115200, ReadTimeout: time.Second, Size: 8,
+		Parity: serial.ParityNone, StopBits: serial.Stop1}
+	if cfg := dev.GetPortConfig(); cfg == nil || *cfg != want {
+		t.Errorf("GetPortConfig() = %+v, want %+v", cfg, want)
+	}
+	if d := dev.GetPortDetails(); !strings.Contains(d, "Baud: 115200") {
+		t.Errorf("GetPortDetails() = %q, want the configured baud", d)
+	}
+
+	dev, _ = Open("COM1", &MockSerialPort{})
+	if cfg := dev.GetPortConfig(); cfg == nil || cfg.Baud != 9600 || cfg.ReadTimeout != 5*time.Second {
+		t.Errorf("Open defaults = %+v, want 9600 baud and 5s timeout", cfg)
+	}
+}
+
 func TestDevice_GetHardwareInfoAndVariant(t *testing.T) {
 	dev := &Device{variant: VariantV2, hardwareInfo: getHardwareInfo(VariantV2)}
 	if dev.GetHardwareVariant() != VariantV2 {
@@ -130,6 +200,18 @@ func TestDevice_SetSweepConfig_Validation(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for too many points")
 	}
+	err = dev.SetSweepConfig(2000000, 1000000, 101) // reversed range
+	if err == nil {
+		t.Error("Expected error for startHz above stopHz")
+	}
+	err = dev.SetSweepConfig(1000000, 1000000, 101) // zero-width range
+	if err == nil {
+		t.Error("Expected error for startHz equal to stopHz")
+	}
+	err = dev.SetSweepConfig(1000000, 2000000, 1) // single point
+	if err == nil {
+		t.Error("Expected error for fewer than 2 points")
+	}
 }
 
 func TestDevice_GetVersion_Default(t *testing.T) {
@@ -139,23 +221,53 @@ func TestDevice_GetVersion_Default(t *testing.T) {
 	}
 }
 
-func TestDevice_CalibrationStubs(t *testing.T) {
-	dev := &Device{}
-	_, err := dev.GetCalibration()
-	if err != nil {
-		t.Error("GetCalibration should not error (stub)")
+func TestDevice_SaveLoadCalibration(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"save 1":   shellResponse("save 1"),
+		"recall 1": shellResponse("recall 1"),
+		"recall 4": shellResponse("recall 4", "error: slot 4 is empty"),
+	}}
+	dev, _ := Open("COM1", port)
+	if err := dev.SaveCalibration(1); err != nil {
+		t.Fatalf("SaveCalibration failed: %v", err)
 	}
-	err = dev.SetCalibration(CalibrationData{})
-	if err != nil {
-		t.Error("SetCalibration should not error (stub)")
+	if last := port.Written[len(port.Written)-1]; last != "save 1" {
+		t.Errorf("SaveCalibration wrote %q, want %q", last, "save 1")
 	}
-	err = dev.SaveCalibration(1)
-	if err != nil {
-		t.Error("SaveCalibration should not error (stub)")
+	if err := dev.LoadCalibration(1); err != nil {
+		t.Fatalf("LoadCalibration failed: %v", err)
 	}
-	err = dev.LoadCalibration(1)
-	if err != nil {
-		t.Error("LoadCalibration should not error (stub)")
+	if last := port.Written[len(port.Written)-1]; last != "recall 1" {
+		t.Errorf("LoadCalibration wrote %q, want %q", last, "recall 1")
+	}
+	if err := dev.LoadCalibration(4); err == nil {
+		t.Error("Expected error when the firmware reports a failure")
+	}
+
+	n := len(port.Written)
+	if err := dev.SaveCalibration(5); err == nil {
+		t.Error("Expected error for a slot beyond the default count")
+	}
+	if err := dev.SaveCalibration(-1); err == nil {
+		t.Error("Expected error for a negative slot")
+	}
+	for _, w := range port.Written[n:] {
+		if strings.HasPrefix(w, "save") {
+			t.Errorf("invalid slot reached the device as %q", w)
+		}
+	}
+
+	dev.hardwareInfo = getHardwareInfo(VariantVH)
+	port.Responses["save 6"] = shellResponse("save 6")
+	if err := dev.SaveCalibration(6); err != nil {
+		t.Errorf("NanoVNA-H profile should accept slot 6: %v", err)
+	}
+	if err := dev.SaveCalibration(7); err == nil {
+		t.Error("Expected error for a slot beyond the NanoVNA-H count")
+	}
+
+	if err := (&Device{}).SaveCalibration(0); err == nil {
+		t.Error("Expected error without an open device")
 	}
 }
 
@@ -201,3 +313,765 @@ func TestDevice_IsPortSupported(t *testing.T) {
 		t.Error("FOO should not be supported")
 	}
 }
+
+func TestDevice_EnterDFU(t *testing.T) {
+	port := &ScriptedSerialPort{}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantVH
+	if err := dev.EnterDFU(); err != nil {
+		t.Fatalf("EnterDFU failed: %v", err)
+	}
+	if len(port.Written) != 1 || port.Written[0] != "reset dfu" {
+		t.Errorf("unexpected commands written: %q", port.Written)
+	}
+	if !port.Closed || dev.GetPortHandle() != nil {
+		t.Error("port should be closed after EnterDFU")
+	}
+	if err := dev.EnterDFU(); err == nil {
+		t.Error("Expected error on closed device")
+	}
+}
+
+func TestDevice_RunSweep_ZeroPadS21(t *testing.T) {
+	newDev := func() *Device {
+		port := &ScriptedSerialPort{Responses: map[string]string{
+			"frequencies": shellResponse("frequencies", "1000000", "2000000"),
+			"data 0":      shellResponse("data 0", "0.1 0.2", "0.3 0.4"),
+		}}
+		dev, _ := Open("COM1", port)
+		dev.variant = VariantV1
+		dev.hardwareInfo = getHardwareInfo(VariantV1)
+		return dev
+	}
+
+	data, err := newDev().RunSweep()
+	if err != nil {
+		t.Fatalf("RunSweep failed: %v", err)
+	}
+	if len(data.S21) != 2 || data.S21[0] != 0 {
+		t.Errorf("default should zero-pad S21, got %v", data.S21)
+	}
+
+	dev := newDev()
+	dev.SetZeroPadS21(false)
+	data, err = dev.RunSweep()
+	if err != nil {
+		t.Fatalf("RunSweep failed: %v", err)
+	}
+	if data.S21 != nil {
+		t.Errorf("S21 should be nil without padding, got %v", data.S21)
+	}
+}
+
+func TestDevice_RunSweep_ReversePorts(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"freq":   shellResponse("freq", "1000000", "2000000"),
+		"data 0": shellResponse("data 0", "0.1 0.2", "0.3 0.4"),
+		"data 1": shellResponse("data 1", "0.5 0", "0.6 0"),
+	}}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantV2Plus4
+	dev.hardwareInfo = getHardwareInfo(VariantV2Plus4)
+	dev.hardwareInfo.CommandSet.PromptPattern = "ch>"
+
+	data, err := dev.RunSweep()
+	if err != nil {
+		t.Fatalf("RunSweep failed: %v", err)
+	}
+	// data 2-6 are calibration terms, not reverse-direction parameters
+	for _, w := range port.Written {
+		if w == "data 2" || w == "data 3" {
+			t.Errorf("RunSweep should not query %q", w)
+		}
+	}
+	if data.S12 != nil || data.S22 != nil {
+		t.Errorf("S12/S22 should be absent, got %v/%v", data.S12, data.S22)
+	}
+}
+
+// cancelPort cancels a context when a given command is written.
+type cancelPort struct {
+	ScriptedSerialPort
+	On     string
+	Cancel context.CancelFunc
+}
+
+func (c *cancelPort) Write(p []byte) (int, error) {
+	if strings.TrimRight(string(p), "\r\n") == c.On {
+		c.Cancel()
+	}
+	return c.ScriptedSerialPort.Write(p)
+}
+
+func TestDevice_RunSweepContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	port := &cancelPort{
+		ScriptedSerialPort: ScriptedSerialPort{Responses: map[string]string{
+			"frequencies": shellResponse("frequencies", "1000000", "2000000"),
+			"data 0":      shellResponse("data 0", "0.1 0.2", "0.3 0.4"),
+		}},
+		On:     "frequencies",
+		Cancel: cancel,
+	}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantV1
+	dev.hardwareInfo = getHardwareInfo(VariantV1)
+
+	if _, err := dev.RunSweepContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunSweepContext error = %v, want context.Canceled", err)
+	}
+	if strings.Contains(strings.Join(port.Written, ","), "data 0") {
+		t.Errorf("S11 queried after cancellation: %q", port.Written)
+	}
+
+	port.On = ""
+	if _, err := dev.RunSweepContext(context.Background()); err != nil {
+		t.Errorf("RunSweepContext failed: %v", err)
+	}
+}
+
+// chunkPort answers any write with Reply, a few bytes per read, followed by
+// empty reads.
+type chunkPort struct {
+	MockSerialPort
+	Reply string
+	Chunk int
+}
+
+func (c *chunkPort) Write(p []byte) (int, error) {
+	c.ReadBuffer, c.ReadIndex = []byte(c.Reply), 0
+	return len(p), nil
+}
+
+func (c *chunkPort) Read(p []byte) (int, error) {
+	if c.ReadIndex >= len(c.ReadBuffer) {
+		return 0, nil
+	}
+	if len(p) > c.Chunk {
+		p = p[:c.Chunk]
+	}
+	return c.MockSerialPort.Read(p)
+}
+
+func TestDevice_SendCommandContext(t *testing.T) {
+	// A long response in small chunks is read through to the prompt
+	body := strings.Repeat("0.1 0.2\r\n", 40)
+	port := &chunkPort{Reply: body + "ch> ", Chunk: 8}
+	dev, _ := Open("COM1", port)
+	resp, err := dev.sendCommandContext(context.Background(), "data 0")
+	if err != nil {
+		t.Fatalf("sendCommandContext failed: %v", err)
+	}
+	if !strings.HasSuffix(resp, "ch> ") || len(resp) != len(body)+4 {
+		t.Errorf("response truncated: got %d bytes, want %d", len(resp), len(body)+4)
+	}
+
+	// A device that stops mid-response gives up at the context deadline
+	port = &chunkPort{Reply: "0.1 0.2\r\n", Chunk: 8}
+	dev, _ = Open("COM1", port)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := dev.GetRawInfoContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetRawInfoContext error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > responseQuietPeriod {
+		t.Errorf("GetRawInfoContext took %v, want to stop at the deadline", elapsed)
+	}
+}
+
+func TestDevice_SendCommandV2Prompt(t *testing.T) {
+	port := &chunkPort{Reply: "info\r\nBoard: NanoVNA V2\r\n2> ", Chunk: 64}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantV2
+	dev.hardwareInfo = getHardwareInfo(VariantV2)
+
+	start := time.Now()
+	resp, err := dev.sendCommand("info")
+	if err != nil {
+		t.Fatalf("sendCommand failed: %v", err)
+	}
+	if !strings.HasSuffix(resp, "2> ") {
+		t.Errorf("response = %q, want it to end with the 2> prompt", resp)
+	}
+	// Without prompt detection the read would only end after the quiet period
+	if elapsed := time.Since(start); elapsed >= responseQuietPeriod {
+		t.Errorf("sendCommand took %v, want it to end at the 2> prompt", elapsed)
+	}
+}
+
+func TestDevice_ConcurrentCommands(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"info":    shellResponse("info", "Board: NanoVNA-H"),
+		"version": shellResponse("version", "1.2.00"),
+	}}
+	dev, _ := Open("COM1", port)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for g := 0; g < 10; g++ {
+		cmd, want := "info", "Board: NanoVNA-H"
+		if g%2 == 1 {
+			cmd, want = "version", "1.2.00"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				lines, err := dev.readLines(cmd)
+				if err != nil || len(lines) != 1 || lines[0] != want {
+					errs <- fmt.Errorf("%s: got %q, %v", cmd, lines, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDevice_GetRawInfo(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"info": shellResponse("info", "Board: NanoVNA-H", "2019-2020 Copyright @edy555", "HW Revision: 3.4"),
+	}}
+	dev, _ := Open("COM1", port)
+	raw, err := dev.GetRawInfo()
+	if err != nil {
+		t.Fatalf("GetRawInfo failed: %v", err)
+	}
+	want := "Board: NanoVNA-H\n2019-2020 Copyright @edy555\nHW Revision: 3.4"
+	if raw != want {
+		t.Errorf("GetRawInfo() = %q, want %q", raw, want)
+	}
+}
+
+func TestDevice_SetCommandTerminator(t *testing.T) {
+	mock := &MockSerialPort{}
+	dev, _ := Open("COM1", mock)
+	dev.sendCommand("info")
+	if string(mock.WriteBuffer) != "info\r" {
+		t.Errorf("default terminator wrote %q", mock.WriteBuffer)
+	}
+
+	mock.WriteBuffer = nil
+	dev.SetCommandTerminator("\r\n")
+	dev.sendCommand("info")
+	if string(mock.WriteBuffer) != "info\r\n" {
+		t.Errorf("custom terminator wrote %q", mock.WriteBuffer)
+	}
+}
+
+func TestDevice_DataChannelCount(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"data 0": shellResponse("data 0", "0.1 0.2"),
+		"data 1": shellResponse("data 1", "0.3 0.4"),
+		"data 2": shellResponse("data 2", "data?"),
+	}}
+	dev, _ := Open("COM1", port)
+	n, err := dev.DataChannelCount()
+	if err != nil || n != 2 {
+		t.Fatalf("DataChannelCount() = %d, %v; want 2", n, err)
+	}
+
+	probes := len(port.Written)
+	if n, _ := dev.DataChannelCount(); n != 2 || len(port.Written) != probes {
+		t.Error("DataChannelCount should use the cached result")
+	}
+}
+
+func TestDevice_Transaction(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"version": shellResponse("version", "1.2.00"),
+	}}
+	dev, _ := Open("COM1", port)
+	resp, err := dev.Transaction([]byte("version\r"), func(b []byte) bool {
+		return strings.Contains(string(b), "ch>")
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if !strings.Contains(string(resp), "1.2.00") {
+		t.Errorf("unexpected response %q", resp)
+	}
+
+	_, err = dev.Transaction([]byte("silent\r"), func(b []byte) bool { return false }, 50*time.Millisecond)
+	if err == nil {
+		t.Error("Expected timeout error")
+	}
+}
+
+func TestDevice_ApplyDefaultSweep(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 50000 1500000000 101": shellResponse("sweep 50000 1500000000 101"),
+	}}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantVH
+	dev.hardwareInfo = getHardwareInfo(VariantVH)
+	if err := dev.ApplyDefaultSweep(); err != nil {
+		t.Fatalf("ApplyDefaultSweep failed: %v", err)
+	}
+	cfg, ok := dev.GetSweepConfig()
+	want := SweepConfig{StartHz: 50000, StopHz: 1500000000, Points: 101}
+	if !ok || cfg != want {
+		t.Errorf("GetSweepConfig() = %+v, %v; want %+v", cfg, ok, want)
+	}
+}
+
+func TestDevice_SetSweepByResolution(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 1500000 51":  shellResponse("sweep 1000000 1500000 51"),
+		"sweep 1000000 2000000 101": shellResponse("sweep 1000000 2000000 101"),
+	}}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantV1
+	dev.hardwareInfo = getHardwareInfo(VariantV1)
+
+	if err := dev.SetSweepByResolution(1000000, 1500000, 10000); err != nil {
+		t.Fatalf("SetSweepByResolution failed: %v", err)
+	}
+	if cfg, _ := dev.GetSweepConfig(); cfg.Points != 51 || cfg.Resolution() != 10000 {
+		t.Errorf("got %+v (resolution %g), want 51 points at 10000 Hz", cfg, cfg.Resolution())
+	}
+
+	// 1 kHz would need 1001 points; clamped to the V1 maximum of 101
+	if err := dev.SetSweepByResolution(1000000, 2000000, 1000); err != nil {
+		t.Fatalf("SetSweepByResolution failed: %v", err)
+	}
+	if cfg, _ := dev.GetSweepConfig(); cfg.Points != 101 || cfg.Resolution() != 10000 {
+		t.Errorf("got %+v (resolution %g), want 101 points at 10000 Hz", cfg, cfg.Resolution())
+	}
+
+	if err := dev.SetSweepByResolution(1000000, 2000000, 0); err == nil {
+		t.Error("Expected error for zero resolution")
+	}
+	if err := dev.SetSweepByResolution(2000000, 1000000, 1000); err == nil {
+		t.Error("Expected error for stop below start")
+	}
+}
+
+// bootingPort ignores the first Boot writes, emulating a device whose shell
+// is not up yet, then behaves like the wrapped ScriptedSerialPort.
+type bootingPort struct {
+	ScriptedSerialPort
+	Boot int
+}
+
+func (b *bootingPort) Write(p []byte) (int, error) {
+	if b.Boot > 0 {
+		b.Boot--
+		return len(p), nil
+	}
+	return b.ScriptedSerialPort.Write(p)
+}
+
+func TestDevice_DetectVersion_Retries(t *testing.T) {
+	newPort := func() *bootingPort {
+		// One attempt writes three bare terminators and "info"
+		return &bootingPort{Boot: 4, ScriptedSerialPort: ScriptedSerialPort{Responses: map[string]string{
+			"":     "ch> ",
+			"info": shellResponse("info", "NanoVNA"),
+		}}}
+	}
+
+	dev, _ := Open("COM1", newPort())
+	if _, err := dev.DetectVersion(); err == nil {
+		t.Error("Expected detection to fail without retries")
+	}
+
+	dev, _ = Open("COM1", newPort())
+	dev.SetDetectRetries(1)
+	version, err := dev.DetectVersion()
+	if err != nil || version != "v1" {
+		t.Errorf("DetectVersion() = %q, %v; want v1", version, err)
+	}
+}
+
+// splitPort delivers the reply to a bare terminator in separate reads with
+// an empty read between them, like a device answering during enumeration.
+type splitPort struct {
+	ScriptedSerialPort
+	Parts []string
+	queue []string
+}
+
+func (p *splitPort) Write(b []byte) (int, error) {
+	if strings.TrimRight(string(b), "\r\n") == "" && p.queue == nil {
+		for _, part := range p.Parts {
+			p.queue = append(p.queue, "", part)
+		}
+	}
+	return p.ScriptedSerialPort.Write(b)
+}
+
+func (p *splitPort) Read(b []byte) (int, error) {
+	if len(p.queue) == 0 {
+		return p.ScriptedSerialPort.Read(b)
+	}
+	part := p.queue[0]
+	p.queue = p.queue[1:]
+	if part == "" {
+		return 0, errors.New("timeout")
+	}
+	return copy(b, part), nil
+}
+
+func TestDevice_DetectVersion_SplitPrompt(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []string
+		want  HardwareVariant
+	}{
+		{"VH prompt in two chunks", []string{"\r\nc", "h> "}, VariantVH},
+		{"V1 prompt after noise", []string{"\x00\xff", "ch> "}, VariantV1},
+		{"V2 prompt in two chunks", []string{"\r\n", "2> "}, VariantV2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := &splitPort{Parts: tt.parts, ScriptedSerialPort: ScriptedSerialPort{Responses: map[string]string{
+				"info": shellResponse("info", "NanoVNA"),
+			}}}
+			dev, _ := Open("COM1", port)
+			if _, err := dev.DetectVersion(); err != nil {
+				t.Fatalf("DetectVersion failed: %v", err)
+			}
+			if dev.GetHardwareVariant() != tt.want {
+				t.Errorf("detected %s, want %s", dev.GetHardwareVariant(), tt.want)
+			}
+			if len(port.Written) != 2 {
+				t.Errorf("expected the first terminator to succeed, wrote %q", port.Written)
+			}
+		})
+	}
+}
+
+func TestDevice_RunSweep_InterleavedFrequency(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"frequencies": shellResponse("frequencies", "1000000", "2000000"),
+		"data 0":      shellResponse("data 0", "1000000 0.1 0.2", "2000000 0.3 0.4"),
+	}}
+	dev, _ := Open("COM1", port)
+	for sweep := 0; sweep < 2; sweep++ {
+		data, err := dev.RunSweep()
+		if err != nil {
+			t.Fatalf("RunSweep failed: %v", err)
+		}
+		if len(data.S11) != 2 || data.S11[1] != complex(0.3, 0.4) || data.Frequencies[1] != 2e6 {
+			t.Errorf("sweep %d: unexpected data %+v", sweep, data)
+		}
+	}
+	if n := strings.Count(strings.Join(port.Written, ","), "frequencies"); n != 1 {
+		t.Errorf("frequencies queried %d times, want 1", n)
+	}
+}
+
+func TestDevice_RunSweep_ReversedOrder(t *testing.T) {
+	newDev := func() (*Device, *[]error) {
+		port := &ScriptedSerialPort{Responses: map[string]string{
+			"frequencies": shellResponse("frequencies", "1000000", "2000000"),
+			"data 0":      shellResponse("data 0", "0.1 0.2", "0.3 0.4"),
+		}}
+		dev, _ := Open("COM1", port)
+		dev.sweepConfig = SweepConfig{StartHz: 2000000, StopHz: 1000000, Points: 2}
+		var diags []error
+		dev.SetDiagnosticHandler(func(err error) { diags = append(diags, err) })
+		return dev, &diags
+	}
+
+	dev, diags := newDev()
+	data, err := dev.RunSweep()
+	if err != nil {
+		t.Fatalf("RunSweep failed: %v", err)
+	}
+	if len(*diags) != 1 || !errors.Is((*diags)[0], ErrSweepOrder) {
+		t.Errorf("expected one ErrSweepOrder diagnostic, got %v", *diags)
+	}
+	if data.S11[0] != complex(0.1, 0.2) {
+		t.Error("data should be returned as read without alignment")
+	}
+
+	dev, _ = newDev()
+	dev.SetAlignSweepOrder(true)
+	data, _ = dev.RunSweep()
+	if data.Frequencies[0] != 1e6 || data.S11[0] != complex(0.3, 0.4) {
+		t.Errorf("aligned data = %+v", data)
+	}
+}
+
+func TestDevice_SetSweepConfigLiteVNA(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 50000 6000000000 2001": shellResponse("sweep 50000 6000000000 2001"),
+	}}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantLiteVNA
+	dev.hardwareInfo = getHardwareInfo(VariantLiteVNA)
+	if err := dev.SetSweepConfig(50000, 6000000000, 2001); err != nil {
+		t.Fatalf("SetSweepConfig rejected a valid LiteVNA sweep: %v", err)
+	}
+	if err := dev.SetSweepConfig(50000, 6500000000, 101); err == nil {
+		t.Error("Expected error above the LiteVNA's 6.3 GHz limit")
+	}
+}
+
+func TestHardwareInfo_Validate(t *testing.T) {
+	for v := VariantUnknown; v <= VariantLiteVNA; v++ {
+		hi := getHardwareInfo(v)
+		if err := hi.Validate(); err != nil {
+			t.Errorf("%s: %v", v, err)
+		}
+		if hi.Variant != v {
+			t.Errorf("getHardwareInfo(%s) returned the %s profile", v, hi.Variant)
+		}
+	}
+
+	bad := getHardwareInfo(VariantV1)
+	bad.SupportedPorts = []string{"S11"}
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected error for HasS21 without S21 port")
+	}
+	bad = getHardwareInfo(VariantV1)
+	bad.CommandSet.DataCommand = "data"
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected error for DataCommand without a channel verb")
+	}
+}
+
+func TestDevice_GetSetCalibration(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"frequencies": shellResponse("frequencies", "1000000", "2000000"),
+		"data 2":      shellResponse("data 2", "0.01 0", "0.02 0"),
+		"data 3":      shellResponse("data 3", "0.1 0.1", "0.1 0.2"),
+		"data 4":      shellResponse("data 4", "0.9 0", "0.8 0"),
+		"data 5":      shellResponse("data 5", "data?"),
+	}}
+	dev, _ := Open("COM1", port)
+	cal, err := dev.GetCalibration()
+	if err != nil {
+		t.Fatalf("GetCalibration failed: %v", err)
+	}
+	if !cal.Valid() || cal.HasThrough() || cal.SourceMatch[1] != complex(0.1, 0.2) {
+		t.Errorf("unexpected calibration %+v", cal)
+	}
+
+	if err := dev.SetCalibration(cal); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetCalibration should be unsupported, got %v", err)
+	}
+	port.Responses["data 2"] = shellResponse("data 2", "0.05 0", "0.02 0")
+	back, err := dev.GetCalibration()
+	if err != nil || back.Directivity[0] != 0.05 {
+		t.Errorf("GetCalibration should read the device terms again: %+v, %v", back, err)
+	}
+}
+
+// flakyPort fails the first Failures writes, like a USB CDC link dropping out.
+type flakyPort struct {
+	ScriptedSerialPort
+	Failures int
+}
+
+func (f *flakyPort) Write(p []byte) (int, error) {
+	if f.Failures > 0 {
+		f.Failures--
+		return 0, errors.New("input/output error")
+	}
+	return f.ScriptedSerialPort.Write(p)
+}
+
+func TestDevice_SetRetryConfig(t *testing.T) {
+	newPort := func() *flakyPort {
+		return &flakyPort{
+			ScriptedSerialPort: ScriptedSerialPort{Responses: map[string]string{
+				"version": shellResponse("version", "1.2.3"),
+			}},
+			Failures: 2,
+		}
+	}
+
+	dev, _ := Open("COM1", newPort())
+	if _, err := dev.sendCommand("version"); err == nil {
+		t.Error("expected the first failure to be returned without retries")
+	}
+
+	port := newPort()
+	dev, _ = Open("COM1", port)
+	dev.SetRetryConfig(RetryConfig{MaxAttempts: 3, Backoff: time.Millisecond})
+	resp, err := dev.sendCommand("version")
+	if err != nil || !strings.Contains(resp, "1.2.3") {
+		t.Fatalf("sendCommand with retries = %q, %v", resp, err)
+	}
+	if len(port.Written) != 1 {
+		t.Errorf("command reached the device %d times, want 1", len(port.Written))
+	}
+
+	// Commands that change device state are never repeated
+	port = newPort()
+	port.Responses["save 1"] = shellResponse("save 1")
+	dev.SetPortHandle(port)
+	if _, err := dev.SendRawCommand("version"); err == nil {
+		t.Error("SendRawCommand should not be retried")
+	}
+	if err := dev.SaveCalibration(1); err == nil || len(port.Written) != 0 {
+		t.Errorf("SaveCalibration should not be retried: %v, wrote %q", err, port.Written)
+	}
+
+	dev.SetPortHandle(nil)
+	dev.SetRetryConfig(RetryConfig{MaxAttempts: 3, Backoff: time.Hour})
+	if _, err := dev.sendCommand("version"); err == nil {
+		t.Error("expected an error without a transport")
+	}
+}
+
+func TestRetryConfig_Delay(t *testing.T) {
+	rc := RetryConfig{Backoff: 10 * time.Millisecond, MaxBackoff: 25 * time.Millisecond}
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 25 * time.Millisecond} {
+		if got := rc.delay(attempt); got != want {
+			t.Errorf("delay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestDevice_SetLogger(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"version": shellResponse("version", "1.2.3"),
+	}}
+	dev, _ := Open("COM1", port)
+	var tx, rx strings.Builder
+	dev.SetLogger(func(direction string, data []byte) {
+		switch direction {
+		case "TX":
+			tx.Write(data)
+		case "RX":
+			rx.Write(data)
+		default:
+			t.Errorf("unexpected direction %q", direction)
+		}
+	})
+	resp, err := dev.sendCommand("version")
+	if err != nil {
+		t.Fatalf("sendCommand failed: %v", err)
+	}
+	if tx.String() != "version\r" {
+		t.Errorf("logged TX %q, want %q", tx.String(), "version\r")
+	}
+	if rx.String() != resp {
+		t.Errorf("logged RX %q, want the full response %q", rx.String(), resp)
+	}
+
+	dev.SetLogger(nil)
+	if _, err := dev.sendCommand("version"); err != nil {
+		t.Errorf("sendCommand without logger failed: %v", err)
+	}
+}
+
+func TestDevice_SendRawCommand(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"threshold": shellResponse("threshold", "current: 300000000"),
+	}}
+	dev, _ := Open("COM1", port)
+	resp, err := dev.SendRawCommand("threshold")
+	if err != nil {
+		t.Fatalf("SendRawCommand failed: %v", err)
+	}
+	if want := "threshold\r\ncurrent: 300000000\r\n"; resp != want {
+		t.Errorf("SendRawCommand = %q, want %q", resp, want)
+	}
+}
+
+func TestDevice_UnsupportedCommand(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"bandwidth 3": shellResponse("bandwidth 3", "bandwidth?"),
+		"threshold":   shellResponse("threshold", "?"),
+		"version":     shellResponse("version", "1.2.00"),
+	}}
+	dev, _ := Open("COM1", port)
+	for _, cmd := range []string{"bandwidth 3", "threshold"} {
+		_, err := dev.SendRawCommand(cmd)
+		if !errors.Is(err, ErrUnsupportedCommand) || !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: expected ErrUnsupportedCommand, got %v", cmd, err)
+		}
+	}
+	if _, err := dev.SendRawCommand("version"); err != nil {
+		t.Errorf("version: unexpected error %v", err)
+	}
+}
+
+func TestDetectOnPort(t *testing.T) {
+	var tried []int
+	var ports []*ScriptedSerialPort
+	open := func(port string, cfg OpenConfig) (*Device, error) {
+		tried = append(tried, cfg.Baud)
+		sp := &ScriptedSerialPort{Responses: map[string]string{}}
+		if cfg.Baud == 115200 {
+			sp.Responses[""] = "ch> "
+			sp.Responses["info"] = shellResponse("info", "NanoVNA")
+		}
+		ports = append(ports, sp)
+		return OpenWithConfig(port, cfg, sp)
+	}
+
+	dev, err := detectOnPort("COM3", []int{9600, 115200, 921600}, open)
+	if err != nil {
+		t.Fatalf("detectOnPort failed: %v", err)
+	}
+	if len(tried) != 2 || tried[1] != 115200 || dev.GetPortConfig().Baud != 115200 {
+		t.Errorf("tried bauds %v, want to stop at 115200", tried)
+	}
+	if !ports[0].Closed || ports[1].Closed {
+		t.Error("only the failed attempt should be closed")
+	}
+
+	if _, err := detectOnPort("COM3", []int{9600}, open); err == nil {
+		t.Error("Expected error when no baud rate answers")
+	}
+}
+
+func TestDevice_Reconnect(t *testing.T) {
+	old := &ScriptedSerialPort{}
+	fresh := &ScriptedSerialPort{Responses: map[string]string{
+		"":     "\r\nch> ",
+		"info": shellResponse("info", "NanoVNA-H"),
+	}}
+	var opened []*serial.Config
+	defer func(orig func(*serial.Config) (Transport, error)) { openSerial = orig }(openSerial)
+	openSerial = func(c *serial.Config) (Transport, error) {
+		opened = append(opened, c)
+		if len(opened) == 1 {
+			return old, nil
+		}
+		return fresh, nil
+	}
+	dev, err := OpenWithConfig("/dev/ttyACM0", OpenConfig{Baud: 115200})
+	if err != nil {
+		t.Fatalf("OpenWithConfig failed: %v", err)
+	}
+
+	if err := dev.Reconnect(); err != nil {
+		t.Fatalf("Reconnect failed: %v", err)
+	}
+	if !old.Closed {
+		t.Error("the old transport should be closed")
+	}
+	if len(opened) != 2 || opened[1].Name != "/dev/ttyACM0" || opened[1].Baud != 115200 {
+		t.Errorf("reopened with %+v, want /dev/ttyACM0 at 115200 baud", opened)
+	}
+	if dev.GetPortHandle() != fresh || dev.GetHardwareVariant() != VariantVH {
+		t.Errorf("after Reconnect variant = %s, want %s on the new transport", dev.GetHardwareVariant(), VariantVH)
+	}
+
+	openSerial = func(c *serial.Config) (Transport, error) {
+		return nil, errors.New("no such file or directory")
+	}
+	if err := dev.Reconnect(); err == nil {
+		t.Error("Expected error when the port cannot be reopened")
+	}
+
+	custom := &ScriptedSerialPort{}
+	dev, _ = Open("bridge:23", custom)
+	if err := dev.Reconnect(); err == nil {
+		t.Error("Expected error reconnecting a custom transport")
+	}
+	if custom.Closed || dev.GetPortHandle() != custom {
+		t.Error("a custom transport should be left open when it cannot be reopened")
+	}
+}
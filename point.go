@@ -0,0 +1,120 @@
+package nanovna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// measurePoints measures each frequency with a single-point sweep, waiting
+// dwell after configuring each one, and assembles the points in the given
+// order. S21 is included only when every point returned it. It stops early
+// with ctx.Err() when ctx is done. Each finished point is counted against
+// progress when it is non-nil.
+func (d *Device) measurePoints(ctx context.Context, freqs []int, dwell time.Duration, progress *sweepProgress) (SweepData, error) {
+	var out SweepData
+	progress.expect(len(freqs))
+	for i, freq := range freqs {
+		if err := d.configureSweep(ctx, freq, freq, 1); err != nil {
+			return SweepData{}, fmt.Errorf("point %d: %v", i, err)
+		}
+		select {
+		case <-ctx.Done():
+			return SweepData{}, ctx.Err()
+		case <-time.After(dwell):
+		}
+		pt, err := d.readSweep(ctx, nil)
+		if err != nil {
+			return SweepData{}, fmt.Errorf("point %d: %v", i, err)
+		}
+		out.Frequencies = append(out.Frequencies, pt.Frequencies[0])
+		out.S11 = append(out.S11, pt.S11[0])
+		if len(pt.S21) > 0 {
+			out.S21 = append(out.S21, pt.S21[0])
+		}
+		out.Z0 = pt.Z0
+		progress.advance(1)
+	}
+	if len(out.S21) != len(out.S11) {
+		out.S21 = nil
+	}
+	return out, nil
+}
+
+// MeasureFrequencies measures each listed frequency, such as amateur band
+// centres, with a single-point sweep and returns the results as one
+// SweepData in the order given. Every frequency is validated against the
+// hardware range before anything is measured. The sweep configuration in
+// effect beforehand is restored afterwards; on a Device that has not set
+// one, the device's own sweep is read from its frequency list first so
+// that it can be restored too.
+func (d *Device) MeasureFrequencies(freqs []int) (SweepData, error) {
+	return d.MeasureFrequenciesContext(context.Background(), freqs)
+}
+
+// MeasureFrequenciesContext is like MeasureFrequencies but stops waiting for
+// the device once ctx is done.
+func (d *Device) MeasureFrequenciesContext(ctx context.Context, freqs []int) (SweepData, error) {
+	if len(freqs) == 0 {
+		return SweepData{}, errors.New("no frequencies to measure")
+	}
+	fr := d.hardwareInfo.FrequencyRange
+	for _, f := range freqs {
+		if float64(f) < fr.MinHz || float64(f) > fr.MaxHz {
+			return SweepData{}, fmt.Errorf("frequency %d Hz is outside %g-%g Hz for %s",
+				f, fr.MinHz, fr.MaxHz, d.variant.String())
+		}
+	}
+
+	if cfg, ok := d.currentSweepConfig(ctx); ok {
+		defer d.restoreSweepConfig(cfg)
+	}
+	return d.measurePoints(ctx, freqs, 0, nil)
+}
+
+// currentSweepConfig returns the sweep the device is running: the
+// configuration last applied through d or, when none has been, the range
+// and point count of the device's own frequency list. The second result is
+// false when neither is available.
+func (d *Device) currentSweepConfig(ctx context.Context) (SweepConfig, bool) {
+	if cfg, ok := d.GetSweepConfig(); ok {
+		return cfg, true
+	}
+	lines, err := d.readLinesContext(ctx, d.hardwareInfo.CommandSet.FreqCommand)
+	if err != nil {
+		return SweepConfig{}, false
+	}
+	var freqs []float64
+	for _, line := range lines {
+		if f, err := strconv.ParseFloat(line, 64); err == nil {
+			freqs = append(freqs, f)
+		}
+	}
+	if len(freqs) < 2 {
+		return SweepConfig{}, false
+	}
+	return SweepConfig{StartHz: int(freqs[0]), StopHz: int(freqs[len(freqs)-1]), Points: len(freqs)}, true
+}
+
+// MeasurePoint measures a single frequency with a one-point sweep, which is
+// far cheaper than a full sweep when watching one frequency while tuning.
+// s21 is zero when the hardware does not report it. Like MeasureFrequencies
+// it validates the frequency and restores the previous sweep configuration.
+func (d *Device) MeasurePoint(freqHz int) (s11, s21 complex128, err error) {
+	return d.MeasurePointContext(context.Background(), freqHz)
+}
+
+// MeasurePointContext is like MeasurePoint but stops waiting for the device
+// once ctx is done.
+func (d *Device) MeasurePointContext(ctx context.Context, freqHz int) (s11, s21 complex128, err error) {
+	data, err := d.MeasureFrequenciesContext(ctx, []int{freqHz})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(data.S21) > 0 {
+		s21 = data.S21[0]
+	}
+	return data.S11[0], s21, nil
+}
@@ -0,0 +1,65 @@
+package nanovna
+
+import "testing"
+
+func TestDevice_MeasureFrequencies(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 3600000 3600000 1":   shellResponse("sweep 3600000 3600000 1"),
+		"sweep 14100000 14100000 1": shellResponse("sweep 14100000 14100000 1"),
+		"frequencies":               shellResponse("frequencies", "3600000"),
+		"data 0":                    shellResponse("data 0", "0.1 0.2"),
+	}}
+	dev, _ := Open("COM1", port)
+	data, err := dev.MeasureFrequencies([]int{3600000, 14100000})
+	if err != nil {
+		t.Fatalf("MeasureFrequencies failed: %v", err)
+	}
+	if len(data.S11) != 2 || len(data.Frequencies) != 2 {
+		t.Errorf("got %d points, want 2", len(data.S11))
+	}
+
+	if _, err := dev.MeasureFrequencies([]int{3600000, 10}); err == nil {
+		t.Error("Expected error for frequency below the hardware range")
+	}
+}
+
+func TestDevice_MeasurePoint(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 7100000 7100000 1": shellResponse("sweep 7100000 7100000 1"),
+		"frequencies":             shellResponse("frequencies", "7100000"),
+		"data 0":                  shellResponse("data 0", "0.1 0.2"),
+		"data 1":                  shellResponse("data 1", "0.5 -0.5"),
+	}}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantV1
+	dev.hardwareInfo = getHardwareInfo(VariantV1)
+	s11, s21, err := dev.MeasurePoint(7100000)
+	if err != nil {
+		t.Fatalf("MeasurePoint failed: %v", err)
+	}
+	if s11 != complex(0.1, 0.2) || s21 != complex(0.5, -0.5) {
+		t.Errorf("MeasurePoint = %v, %v; want (0.1+0.2i), (0.5-0.5i)", s11, s21)
+	}
+
+	if _, _, err := dev.MeasurePoint(1000); err == nil {
+		t.Error("Expected error for frequency below the hardware range")
+	}
+}
+
+func TestDevice_MeasurePoint_RestoresDeviceSweep(t *testing.T) {
+	// A fresh Device has no sweep configuration of its own, so the device's
+	// sweep is taken from its frequency list
+	port := &segmentPort{ScriptedSerialPort{Responses: map[string]string{
+		"frequencies": shellResponse("frequencies", "1000000", "1500000", "2000000"),
+	}}}
+	dev, _ := Open("COM1", port)
+	if _, _, err := dev.MeasurePoint(7100000); err != nil {
+		t.Fatalf("MeasurePoint failed: %v", err)
+	}
+	if last := port.Written[len(port.Written)-1]; last != "sweep 1000000 2000000 3" {
+		t.Errorf("last command = %q, want the device's sweep restored", last)
+	}
+	if cfg, ok := dev.GetSweepConfig(); !ok || cfg.Points != 3 {
+		t.Errorf("GetSweepConfig() = %+v, %v; want the restored 3-point sweep", cfg, ok)
+	}
+}
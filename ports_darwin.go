@@ -0,0 +1,88 @@
+//go:build darwin
+
+package nanovna
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// serialPortPatterns match the callout devices of USB CDC-ACM devices and
+// USB serial adapters.
+var serialPortPatterns = []string{"/dev/cu.usbmodem*", "/dev/cu.usbserial*"}
+
+// listSerialPorts returns the USB serial callout devices under /dev.
+func listSerialPorts() ([]string, error) {
+	return globSerialPorts(serialPortPatterns)
+}
+
+// ioregOutput returns the I/O Registry with all properties, one entry or
+// property per line.
+var ioregOutput = func() ([]byte, error) {
+	return exec.Command("ioreg", "-l", "-w0").Output()
+}
+
+// portUSBID returns the USB ID of callout device name by finding its
+// IOSerialBSDClient in the I/O Registry and walking up to the USB device
+// that carries idVendor and idProduct.
+func portUSBID(name string) (USBID, error) {
+	out, err := ioregOutput()
+	if err != nil {
+		return USBID{}, fmt.Errorf("ioreg: %v", err)
+	}
+
+	// Entries still open above the current line, outermost first
+	type entry struct {
+		depth          int
+		vid, pid       uint16
+		hasVID, hasPID bool
+	}
+	var stack []entry
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "+-o "); i >= 0 {
+			for len(stack) > 0 && stack[len(stack)-1].depth >= i {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, entry{depth: i})
+			continue
+		}
+		if len(stack) == 0 {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimLeft(line, " |"), " = ")
+		if !ok {
+			continue
+		}
+		top := &stack[len(stack)-1]
+		switch key {
+		case `"idVendor"`:
+			v, err := strconv.ParseUint(value, 10, 16)
+			top.vid, top.hasVID = uint16(v), err == nil
+		case `"idProduct"`:
+			v, err := strconv.ParseUint(value, 10, 16)
+			top.pid, top.hasPID = uint16(v), err == nil
+		case `"IOCalloutDevice"`:
+			if filepath.Base(strings.Trim(value, `"`)) != name {
+				continue
+			}
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].hasVID && stack[i].hasPID {
+					return USBID{VID: stack[i].vid, PID: stack[i].pid}, nil
+				}
+			}
+			return USBID{}, fmt.Errorf("%s is not a USB device", name)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return USBID{}, err
+	}
+	return USBID{}, fmt.Errorf("%s not found in the I/O Registry", name)
+}
@@ -0,0 +1,44 @@
+package nanovna
+
+import "testing"
+
+func TestPortUSBID(t *testing.T) {
+	const ioreg = `+-o Root  <class IORegistryEntry, id 0x100000100, retain 20>
+  +-o AppleUSBXHCI@01000000  <class AppleUSBXHCI, id 0x100000300, registered>
+  | {
+  |   "IOClass" = "AppleUSBXHCI"
+  | }
+  | +-o NanoVNA-H@01100000  <class IOUSBHostDevice, id 0x100000400, registered>
+  | | {
+  | |   "idProduct" = 22336
+  | |   "idVendor" = 1155
+  | | }
+  | | +-o IOUSBHostInterface@0  <class IOUSBHostInterface, id 0x100000500, registered>
+  | |   +-o AppleUSBACMControl  <class AppleUSBACMControl, id 0x100000600, registered>
+  | |     +-o IOSerialBSDClient  <class IOSerialBSDClient, id 0x100000700, registered>
+  | |         {
+  | |           "IOCalloutDevice" = "/dev/cu.usbmodem4001"
+  | |         }
+  | +-o IOSerialBSDClient  <class IOSerialBSDClient, id 0x100000800, registered>
+  |     {
+  |       "IOCalloutDevice" = "/dev/cu.debug"
+  |     }
+`
+	saved := ioregOutput
+	ioregOutput = func() ([]byte, error) { return []byte(ioreg), nil }
+	defer func() { ioregOutput = saved }()
+
+	id, err := portUSBID("cu.usbmodem4001")
+	if err != nil {
+		t.Fatalf("portUSBID failed: %v", err)
+	}
+	if id != (USBID{0x0483, 0x5740}) {
+		t.Errorf("portUSBID = %v, want 0483:5740", id)
+	}
+	if _, err := portUSBID("cu.debug"); err == nil {
+		t.Error("Expected error for a callout device outside any USB device")
+	}
+	if _, err := portUSBID("cu.usbserial-1"); err == nil {
+		t.Error("Expected error for a device missing from the registry")
+	}
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package nanovna
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// serialPortPatterns match USB CDC-ACM devices (NanoVNA-H, V2) and USB
+// serial adapters.
+var serialPortPatterns = []string{"/dev/ttyACM*", "/dev/ttyUSB*"}
+
+// listSerialPorts returns the USB serial device nodes under /dev.
+func listSerialPorts() ([]string, error) {
+	return globSerialPorts(serialPortPatterns)
+}
+
+// sysClassTTY is the sysfs directory describing tty devices.
+var sysClassTTY = "/sys/class/tty"
+
+// portUSBID returns the USB ID of tty device name by walking up from its
+// sysfs device directory to the USB device that carries idVendor and
+// idProduct.
+func portUSBID(name string) (USBID, error) {
+	dir, err := filepath.EvalSymlinks(filepath.Join(sysClassTTY, name, "device"))
+	if err != nil {
+		return USBID{}, err
+	}
+	for i := 0; i < 4 && dir != "/"; i, dir = i+1, filepath.Dir(dir) {
+		vid, err := readSysfsHex(filepath.Join(dir, "idVendor"))
+		if err != nil {
+			continue
+		}
+		pid, err := readSysfsHex(filepath.Join(dir, "idProduct"))
+		if err != nil {
+			return USBID{}, err
+		}
+		return USBID{VID: vid, PID: pid}, nil
+	}
+	return USBID{}, fmt.Errorf("%s is not a USB device", name)
+}
+
+// readSysfsHex reads a sysfs attribute holding a 16-bit hexadecimal value.
+func readSysfsHex(path string) (uint16, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 16, 16)
+	return uint16(v), err
+}
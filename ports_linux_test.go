@@ -0,0 +1,41 @@
+package nanovna
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPortUSBID(t *testing.T) {
+	root := t.TempDir()
+	// /sys/bus/usb/devices/1-1 is the USB device, 1-1:1.0 its interface
+	usbDev := filepath.Join(root, "devices", "1-1")
+	iface := filepath.Join(usbDev, "1-1:1.0")
+	if err := os.MkdirAll(iface, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(usbDev, "idVendor"), []byte("0483\n"), 0o644)
+	os.WriteFile(filepath.Join(usbDev, "idProduct"), []byte("5740\n"), 0o644)
+
+	tty := filepath.Join(root, "class", "tty")
+	os.MkdirAll(filepath.Join(tty, "ttyACM0"), 0o755)
+	if err := os.Symlink(iface, filepath.Join(tty, "ttyACM0", "device")); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(tty, "ttyS0"), 0o755)
+
+	saved := sysClassTTY
+	sysClassTTY = tty
+	defer func() { sysClassTTY = saved }()
+
+	id, err := portUSBID("ttyACM0")
+	if err != nil {
+		t.Fatalf("portUSBID failed: %v", err)
+	}
+	if id != (USBID{0x0483, 0x5740}) || id.String() != "0483:5740" {
+		t.Errorf("portUSBID = %v, want 0483:5740", id)
+	}
+	if _, err := portUSBID("ttyS0"); err == nil {
+		t.Error("Expected error for a tty without a device link")
+	}
+}
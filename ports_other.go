@@ -0,0 +1,18 @@
+//go:build !windows && !linux && !darwin
+
+package nanovna
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// listSerialPorts is not implemented on this platform.
+func listSerialPorts() ([]string, error) {
+	return nil, fmt.Errorf("serial port enumeration is not supported on %s", runtime.GOOS)
+}
+
+// portUSBID is not implemented on this platform.
+func portUSBID(name string) (USBID, error) {
+	return USBID{}, errNoUSBLookup
+}
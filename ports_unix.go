@@ -0,0 +1,22 @@
+//go:build linux || darwin
+
+package nanovna
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// globSerialPorts returns the sorted device paths matching any of patterns.
+func globSerialPorts(patterns []string) ([]string, error) {
+	var ports []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, matches...)
+	}
+	sort.Strings(ports)
+	return ports, nil
+}
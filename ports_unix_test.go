@@ -0,0 +1,25 @@
+//go:build linux || darwin
+
+package nanovna
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobSerialPorts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"ttyUSB1", "ttyACM0", "ttyS0"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ports, err := globSerialPorts([]string{filepath.Join(dir, "ttyACM*"), filepath.Join(dir, "ttyUSB*")})
+	if err != nil {
+		t.Fatalf("globSerialPorts failed: %v", err)
+	}
+	if len(ports) != 2 || filepath.Base(ports[0]) != "ttyACM0" || filepath.Base(ports[1]) != "ttyUSB1" {
+		t.Errorf("globSerialPorts = %v, want ttyACM0 and ttyUSB1", ports)
+	}
+}
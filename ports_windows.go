@@ -0,0 +1,27 @@
+//go:build windows
+
+package nanovna
+
+import (
+	"fmt"
+	"os"
+)
+
+// listSerialPorts probes COM1 to COM20 and returns those that can be opened.
+func listSerialPorts() ([]string, error) {
+	var ports []string
+	for i := 1; i <= 20; i++ {
+		portName := fmt.Sprintf("COM%d", i)
+		f, err := os.Open("//./" + portName)
+		if err == nil {
+			ports = append(ports, portName)
+			f.Close()
+		}
+	}
+	return ports, nil
+}
+
+// portUSBID is not implemented on this platform.
+func portUSBID(name string) (USBID, error) {
+	return USBID{}, errNoUSBLookup
+}
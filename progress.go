@@ -0,0 +1,115 @@
+package nanovna
+
+import (
+	"context"
+	"strings"
+)
+
+// sweepProgress turns received sweep rows into the fraction reported by
+// RunSweepProgress. A nil *sweepProgress ignores all calls.
+type sweepProgress struct {
+	cb    func(fraction float64)
+	total int     // Rows expected for the whole sweep
+	done  int     // Rows received so far
+	last  float64 // Last fraction passed to cb
+}
+
+// expect starts counting towards total rows.
+func (p *sweepProgress) expect(total int) {
+	if p == nil {
+		return
+	}
+	p.total, p.done = total, 0
+}
+
+// advance counts n more received rows and reports the new fraction.
+func (p *sweepProgress) advance(n int) {
+	if p == nil || p.total <= 0 {
+		return
+	}
+	p.done += n
+	p.report(float64(p.done) / float64(p.total))
+}
+
+// report passes fraction, capped at 1, to the callback unless it would not
+// move the progress forward. Retried commands resend their rows, so the
+// count alone may briefly overshoot or restart.
+func (p *sweepProgress) report(fraction float64) {
+	if fraction > 1 {
+		fraction = 1
+	}
+	if fraction <= p.last {
+		return
+	}
+	p.last = fraction
+	p.cb(fraction)
+}
+
+// sweepRowCount returns how many payload rows readSweep expects: one per
+// configured point (the firmware default when unconfigured) for each query
+// it will send.
+func (d *Device) sweepRowCount() int {
+	points := d.sweepConfig.Points
+	if points <= 0 {
+		points = defaultSweepPoints
+	}
+	queries := 1 // S11
+	if !d.interleavedData {
+		queries++
+	}
+	if d.hardwareInfo.Capabilities.HasS21 && d.IsPortSupported("S21") {
+		queries++
+	}
+	return points * queries
+}
+
+// readSweepLines is readLinesContext for the queries of a sweep, counting
+// each payload row against progress as soon as it arrives.
+func (d *Device) readSweepLines(ctx context.Context, progress *sweepProgress, cmd string) ([]string, error) {
+	if progress == nil {
+		return d.readLinesContext(ctx, cmd)
+	}
+	var pending string
+	resp, err := d.sendCommandChunks(ctx, cmd, func(chunk string) {
+		pending += chunk
+		for {
+			i := strings.IndexByte(pending, '\n')
+			if i < 0 {
+				return
+			}
+			if d.payloadLine(strings.TrimSpace(pending[:i]), cmd) {
+				progress.advance(1)
+			}
+			pending = pending[i+1:]
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.responseLines(resp, cmd), nil
+}
+
+// RunSweepProgress is like RunSweep but calls cb with the fraction of the
+// sweep received so far, between 0 and 1, as rows arrive from the device,
+// so that a GUI can show progress during multi-second sweeps. The fraction
+// counts parsed rows against the configured point count for every query
+// of the sweep and never decreases; cb receives 1 once the sweep has been
+// read successfully. cb runs while the device's port is locked, so it must
+// not call methods on the Device.
+func (d *Device) RunSweepProgress(cb func(fraction float64)) (SweepData, error) {
+	return d.RunSweepProgressContext(context.Background(), cb)
+}
+
+// RunSweepProgressContext is like RunSweepProgress but stops waiting for the
+// device once ctx is done.
+func (d *Device) RunSweepProgressContext(ctx context.Context, cb func(fraction float64)) (SweepData, error) {
+	if cb == nil {
+		return d.RunSweepContext(ctx)
+	}
+	progress := &sweepProgress{cb: cb}
+	data, err := d.runSweep(ctx, progress)
+	if err == nil {
+		progress.report(1)
+	}
+	return data, err
+}
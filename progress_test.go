@@ -0,0 +1,53 @@
+package nanovna
+
+import "testing"
+
+func TestDevice_RunSweepProgress(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"frequencies": shellResponse("frequencies", "1000000", "2000000"),
+		"data 0":      shellResponse("data 0", "0.1 0.2", "0.3 0.4"),
+		"data 1":      shellResponse("data 1", "0.5 0", "0.6 0"),
+	}}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantV1
+	dev.hardwareInfo = getHardwareInfo(VariantV1)
+	dev.sweepConfig = SweepConfig{StartHz: 1000000, StopHz: 2000000, Points: 2}
+
+	var got []float64
+	data, err := dev.RunSweepProgress(func(f float64) { got = append(got, f) })
+	if err != nil {
+		t.Fatalf("RunSweepProgress failed: %v", err)
+	}
+	if len(data.S11) != 2 {
+		t.Fatalf("got %d points, want 2", len(data.S11))
+	}
+	if want := dev.sweepRowCount(); len(got) != want {
+		t.Fatalf("got %d progress reports %v, want one per row (%d)", len(got), got, want)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Errorf("progress went backwards: %v", got)
+		}
+	}
+	if got[0] <= 0 || got[len(got)-1] != 1 {
+		t.Errorf("progress = %v, want (0, 1] ending at 1", got)
+	}
+}
+
+func TestSweepProgress_Report(t *testing.T) {
+	var got []float64
+	p := &sweepProgress{cb: func(f float64) { got = append(got, f) }}
+	p.expect(4)
+	p.advance(2)
+	p.expect(4) // A restarted sweep must not move the fraction back
+	p.advance(1)
+	p.advance(3)
+	p.advance(1)
+	if want := []float64{0.5, 1}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("reports = %v, want %v", got, want)
+	}
+
+	var nilProgress *sweepProgress
+	nilProgress.expect(1)
+	nilProgress.advance(1)
+}
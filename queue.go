@@ -0,0 +1,141 @@
+package nanovna
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueClosed is returned for measurements requested from, or still
+// pending in, a closed MeasureQueue.
+var ErrQueueClosed = errors.New("measure queue closed")
+
+// MeasureResult is the outcome of a queued measurement.
+type MeasureResult struct {
+	Config SweepConfig
+	Data   SweepData
+	Err    error
+}
+
+// MeasureQueue serializes measurement requests to a Device, enforces a
+// minimum interval between sweeps, and coalesces pending requests for the
+// same SweepConfig into a single sweep whose result is delivered to each of
+// them. Requests are served in arrival order of their first pending request.
+type MeasureQueue struct {
+	dev         *Device
+	minInterval time.Duration
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending map[SweepConfig][]chan MeasureResult
+	order   []SweepConfig
+	closed  bool
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewMeasureQueue starts a queue serving measurements on dev with at least
+// minInterval between the end of one sweep and the start of the next.
+// Call Close to stop it.
+func NewMeasureQueue(dev *Device, minInterval time.Duration) *MeasureQueue {
+	q := &MeasureQueue{
+		dev:         dev,
+		minInterval: minInterval,
+		pending:     make(map[SweepConfig][]chan MeasureResult),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	go q.run()
+	return q
+}
+
+// Request queues a measurement of cfg and returns a channel that receives
+// exactly one result. If an identical request is already pending, both are
+// answered by the same sweep.
+func (q *MeasureQueue) Request(cfg SweepConfig) <-chan MeasureResult {
+	ch := make(chan MeasureResult, 1)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		ch <- MeasureResult{Config: cfg, Err: ErrQueueClosed}
+		close(ch)
+		return ch
+	}
+	if _, ok := q.pending[cfg]; !ok {
+		q.order = append(q.order, cfg)
+	}
+	q.pending[cfg] = append(q.pending[cfg], ch)
+	q.cond.Signal()
+	return ch
+}
+
+// Close stops the queue after any in-progress sweep completes. Requests still
+// pending receive ErrQueueClosed.
+func (q *MeasureQueue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.done)
+		q.cond.Broadcast()
+	}
+	q.mu.Unlock()
+	<-q.stopped
+}
+
+// run is the queue's worker goroutine.
+func (q *MeasureQueue) run() {
+	defer close(q.stopped)
+
+	var last time.Time
+	for {
+		q.mu.Lock()
+		for len(q.order) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			for _, cfg := range q.order {
+				deliver(q.pending[cfg], MeasureResult{Config: cfg, Err: ErrQueueClosed})
+			}
+			q.order, q.pending = nil, nil
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+
+		// Enforce the minimum interval; requests arriving meanwhile coalesce
+		if wait := q.minInterval - time.Since(last); !last.IsZero() && wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-q.done:
+				continue
+			}
+		}
+
+		// Detach the waiters so later requests get a fresh sweep
+		q.mu.Lock()
+		cfg := q.order[0]
+		q.order = q.order[1:]
+		waiters := q.pending[cfg]
+		delete(q.pending, cfg)
+		q.mu.Unlock()
+
+		res := MeasureResult{Config: cfg}
+		if err := q.dev.SetSweepConfigMode(cfg.StartHz, cfg.StopHz, cfg.Points, cfg.Mode); err != nil {
+			res.Err = err
+		} else {
+			res.Data, res.Err = q.dev.RunSweep()
+		}
+		last = time.Now()
+		deliver(waiters, res)
+	}
+}
+
+// deliver sends res to every waiter and closes their channels.
+func deliver(waiters []chan MeasureResult, res MeasureResult) {
+	for _, ch := range waiters {
+		ch <- res
+		close(ch)
+	}
+}
@@ -0,0 +1,49 @@
+package nanovna
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMeasureQueue_CoalescesPendingRequests(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 2000000 2": shellResponse("sweep 1000000 2000000 2"),
+		"sweep 3000000 4000000 2": shellResponse("sweep 3000000 4000000 2"),
+		"frequencies":             shellResponse("frequencies", "1000000", "2000000"),
+		"data 0":                  shellResponse("data 0", "0.1 0.2", "0.3 0.4"),
+	}}
+	dev, _ := Open("COM1", port)
+	q := NewMeasureQueue(dev, 10*time.Millisecond)
+	defer q.Close()
+
+	first := q.Request(SweepConfig{StartHz: 1000000, StopHz: 2000000, Points: 2})
+	cfg := SweepConfig{StartHz: 3000000, StopHz: 4000000, Points: 2}
+	a, b := q.Request(cfg), q.Request(cfg)
+
+	for _, ch := range []<-chan MeasureResult{first, a, b} {
+		res := <-ch
+		if res.Err != nil {
+			t.Fatalf("measurement failed: %v", res.Err)
+		}
+	}
+
+	sweeps := 0
+	for _, cmd := range port.Written {
+		if cmd == "sweep 3000000 4000000 2" {
+			sweeps++
+		}
+	}
+	if sweeps != 1 {
+		t.Errorf("identical pending requests ran %d sweeps, want 1", sweeps)
+	}
+}
+
+func TestMeasureQueue_Closed(t *testing.T) {
+	q := NewMeasureQueue(&Device{}, 0)
+	q.Close()
+	res := <-q.Request(SweepConfig{})
+	if !errors.Is(res.Err, ErrQueueClosed) {
+		t.Errorf("expected ErrQueueClosed, got %v", res.Err)
+	}
+}
@@ -0,0 +1,167 @@
+package nanovna
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// RLCTopology selects the equivalent circuit fitted by FitRLCModel.
+type RLCTopology int
+
+const (
+	RLCSeries   RLCTopology = iota // R, L and C in series (impedance minimum at resonance)
+	RLCParallel                    // R, L and C in parallel (impedance maximum at resonance)
+)
+
+// String returns the string representation of the topology.
+func (t RLCTopology) String() string {
+	switch t {
+	case RLCSeries:
+		return "Series RLC"
+	case RLCParallel:
+		return "Parallel RLC"
+	default:
+		return "Unknown"
+	}
+}
+
+// RLCModel is an equivalent circuit fitted to a measured resonance.
+type RLCModel struct {
+	Topology   RLCTopology
+	R          float64 // Ohms
+	L          float64 // Henries
+	C          float64 // Farads
+	ResonantHz float64 // 1/(2π·√(LC))
+	// FitError is the relative RMS deviation of the model impedance from the
+	// measured impedance over the fitted points, √(Σ|Zfit−Z|² / Σ|Z|²).
+	// Values well below 0.1 indicate the topology describes the data.
+	FitError float64
+	Points   int // Number of sweep points used in the fit
+}
+
+// rlcWindowFactor bounds the fit window: points whose reactance (series) or
+// susceptance (parallel) magnitude exceeds this multiple of the resistive
+// part at resonance are excluded, keeping the fit to about three bandwidths
+// either side of the resonance.
+const rlcWindowFactor = 3
+
+// FitRLC fits both a series and a parallel RLC model to the S11 impedance
+// around the resonance and returns the component values of the topology with
+// the smaller FitError. Use FitRLCModel to force a topology or to inspect the
+// fit quality. z0 is the reference impedance; zero uses the sweep's Z0.
+func (d SweepData) FitRLC(z0 float64) (r, l, c float64, err error) {
+	series, errS := d.FitRLCModel(z0, RLCSeries)
+	parallel, errP := d.FitRLCModel(z0, RLCParallel)
+	switch {
+	case errS != nil && errP != nil:
+		return 0, 0, 0, errS
+	case errP != nil || (errS == nil && series.FitError <= parallel.FitError):
+		return series.R, series.L, series.C, nil
+	default:
+		return parallel.R, parallel.L, parallel.C, nil
+	}
+}
+
+// FitRLCModel fits the given RLC topology to the S11 impedance by linear
+// least squares. For the series model X·ω = L·ω² − 1/C is fitted to the
+// reactance; for the parallel model B·ω = C·ω² − 1/L is fitted to the
+// susceptance. R is the mean resistance (series) or the reciprocal of the
+// mean conductance (parallel). Only points near the resonance are used (see
+// rlcWindowFactor); with fewer than three such points the whole sweep is used.
+// z0 is the reference impedance; zero uses the sweep's Z0.
+func (d SweepData) FitRLCModel(z0 float64, topology RLCTopology) (RLCModel, error) {
+	if topology != RLCSeries && topology != RLCParallel {
+		return RLCModel{}, fmt.Errorf("unknown RLC topology %d", topology)
+	}
+	if len(d.S11) != len(d.Frequencies) || len(d.S11) < 3 {
+		return RLCModel{}, fmt.Errorf("need at least 3 points with S11, got %d", len(d.S11))
+	}
+	if z0 <= 0 {
+		z0 = d.referenceZ0()
+	}
+
+	// Work in impedance for the series model and admittance for the parallel
+	// one; both then have the form v = re + j(a·ω − b/ω).
+	omega := make([]float64, len(d.Frequencies))
+	vals := make([]complex128, len(d.S11))
+	for i, g := range d.S11 {
+		omega[i] = 2 * math.Pi * d.Frequencies[i]
+		z := gammaToImpedance(g, z0)
+		if topology == RLCParallel {
+			vals[i] = 1 / z
+		} else {
+			vals[i] = z
+		}
+	}
+
+	// Resonance is where the imaginary part is smallest
+	res := 0
+	for i, v := range vals {
+		if math.Abs(imag(v)) < math.Abs(imag(vals[res])) {
+			res = i
+		}
+	}
+	var idx []int
+	limit := rlcWindowFactor * math.Abs(real(vals[res]))
+	for i, v := range vals {
+		if math.Abs(imag(v)) <= limit {
+			idx = append(idx, i)
+		}
+	}
+	if len(idx) < 3 {
+		idx = idx[:0]
+		for i := range vals {
+			idx = append(idx, i)
+		}
+	}
+
+	// Least squares for y = a·x − b with x = ω², y = Im(v)·ω
+	var meanX, meanY, meanRe float64
+	for _, i := range idx {
+		meanX += omega[i] * omega[i]
+		meanY += imag(vals[i]) * omega[i]
+		meanRe += real(vals[i])
+	}
+	n := float64(len(idx))
+	meanX, meanY, meanRe = meanX/n, meanY/n, meanRe/n
+	var sxx, sxy float64
+	for _, i := range idx {
+		dx := omega[i]*omega[i] - meanX
+		sxx += dx * dx
+		sxy += dx * (imag(vals[i])*omega[i] - meanY)
+	}
+	if sxx == 0 {
+		return RLCModel{}, errors.New("fit points share one frequency")
+	}
+	a := sxy / sxx
+	b := a*meanX - meanY
+	if a <= 0 || b <= 0 || meanRe <= 0 {
+		return RLCModel{}, fmt.Errorf("data does not fit a %s model", topology)
+	}
+
+	m := RLCModel{Topology: topology, Points: len(idx)}
+	if topology == RLCSeries {
+		m.R, m.L, m.C = meanRe, a, 1/b
+	} else {
+		m.R, m.C, m.L = 1/meanRe, a, 1/b
+	}
+	m.ResonantHz = 1 / (2 * math.Pi * math.Sqrt(m.L*m.C))
+
+	var errSum, magSum float64
+	for _, i := range idx {
+		w := omega[i]
+		fit := complex(meanRe, a*w-b/w)
+		z, zFit := vals[i], fit
+		if topology == RLCParallel {
+			z, zFit = 1/vals[i], 1/fit
+		}
+		errSum += math.Pow(cmplx.Abs(zFit-z), 2)
+		magSum += math.Pow(cmplx.Abs(z), 2)
+	}
+	if magSum > 0 {
+		m.FitError = math.Sqrt(errSum / magSum)
+	}
+	return m, nil
+}
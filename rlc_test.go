@@ -0,0 +1,50 @@
+package nanovna
+
+import (
+	"math"
+	"testing"
+)
+
+// rlcSweep returns S11 of an ideal RLC circuit swept around its resonance.
+func rlcSweep(topology RLCTopology, r, l, c float64) SweepData {
+	f0 := 1 / (2 * math.Pi * math.Sqrt(l*c))
+	var d SweepData
+	for i := 0; i < 101; i++ {
+		f := f0 * (0.9 + 0.2*float64(i)/100)
+		w := 2 * math.Pi * f
+		var z complex128
+		if topology == RLCSeries {
+			z = complex(r, w*l-1/(w*c))
+		} else {
+			z = 1 / complex(1/r, w*c-1/(w*l))
+		}
+		d.Frequencies = append(d.Frequencies, f)
+		d.S11 = append(d.S11, (z-50)/(z+50))
+	}
+	return d
+}
+
+func TestSweepData_FitRLC(t *testing.T) {
+	tests := []struct {
+		topology RLCTopology
+		r, l, c  float64
+	}{
+		{RLCSeries, 10, 1e-6, 100e-12},
+		{RLCParallel, 2000, 1e-6, 100e-12},
+	}
+	for _, tc := range tests {
+		d := rlcSweep(tc.topology, tc.r, tc.l, tc.c)
+		r, l, c, err := d.FitRLC(50)
+		if err != nil {
+			t.Fatalf("%s: FitRLC failed: %v", tc.topology, err)
+		}
+		if math.Abs(r/tc.r-1) > 1e-6 || math.Abs(l/tc.l-1) > 1e-6 || math.Abs(c/tc.c-1) > 1e-6 {
+			t.Errorf("%s: FitRLC = %g, %g, %g; want %g, %g, %g", tc.topology, r, l, c, tc.r, tc.l, tc.c)
+		}
+
+		m, err := d.FitRLCModel(50, tc.topology)
+		if err != nil || m.FitError > 1e-6 {
+			t.Errorf("%s: FitError = %g, %v", tc.topology, m.FitError, err)
+		}
+	}
+}
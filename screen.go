@@ -0,0 +1,101 @@
+package nanovna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+)
+
+// captureTimeout bounds reading a framebuffer dump, which at USB CDC speeds
+// takes well under a second for a 480x320 screen.
+const captureTimeout = 10 * time.Second
+
+// CaptureScreen dumps the LCD framebuffer with the firmware's `capture`
+// command and returns it as an image of the size given by HardwareInfo.Screen.
+// The firmware sends Width·Height RGB565 pixels, two bytes each, most
+// significant byte first. The port lock is taken ahead of queued commands so
+// that a screenshot stays responsive during continuous sweeping.
+func (d *Device) CaptureScreen() (image.Image, error) {
+	return d.CaptureScreenContext(context.Background())
+}
+
+// CaptureScreenContext is like CaptureScreen but stops waiting for the
+// device once ctx is done.
+func (d *Device) CaptureScreenContext(ctx context.Context) (image.Image, error) {
+	size := d.hardwareInfo.Screen
+	if size.Width <= 0 || size.Height <= 0 {
+		return nil, ErrUnsupported
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	timeout := captureTimeout
+	if deadline, has := ctx.Deadline(); has && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	const cmd = "capture"
+	need := size.Width * size.Height * 2
+	resp, err := d.transaction(true, []byte(cmd+d.commandTerminator()), func(b []byte) bool {
+		payload, ok := commandPayload(b, cmd)
+		return ok && (len(payload) >= need || strings.HasPrefix(string(payload), cmd+"?"))
+	}, timeout)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	payload, ok := commandPayload(resp, cmd)
+	if ok && strings.HasPrefix(string(payload), cmd+"?") {
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("screen capture failed: %v", err)
+	}
+	if len(payload) < need {
+		return nil, errors.New("screen capture returned too few bytes")
+	}
+	return decodeRGB565(payload[:need], size.Width, size.Height), nil
+}
+
+// decodeRGB565 converts big-endian RGB565 pixels, row by row, to an RGBA
+// image, expanding each channel to 8 bits.
+func decodeRGB565(pix []byte, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height; i++ {
+		v := uint16(pix[2*i])<<8 | uint16(pix[2*i+1])
+		r, g, b := uint8(v>>11), uint8(v>>5&0x3f), uint8(v&0x1f)
+		img.SetRGBA(i%width, i/width, color.RGBA{
+			R: r<<3 | r>>2,
+			G: g<<2 | g>>4,
+			B: b<<3 | b>>2,
+			A: 0xff,
+		})
+	}
+	return img
+}
+
+// SetBrightness sets the LCD backlight to level on a 0-100 scale, which is
+// mapped onto the firmware's range (HardwareInfo.MaxBrightness) so headless
+// bench setups can dim the screen. Returns ErrUnsupported on variants
+// without an adjustable backlight.
+func (d *Device) SetBrightness(level int) error {
+	return d.SetBrightnessContext(context.Background(), level)
+}
+
+// SetBrightnessContext is like SetBrightness but stops waiting for the
+// device once ctx is done.
+func (d *Device) SetBrightnessContext(ctx context.Context, level int) error {
+	if level < 0 || level > 100 {
+		return fmt.Errorf("brightness %d out of range 0-100", level)
+	}
+	format := d.hardwareInfo.CommandSet.BrightnessCommand
+	if format == "" || d.hardwareInfo.MaxBrightness <= 0 {
+		return ErrUnsupported
+	}
+	raw := (level*d.hardwareInfo.MaxBrightness + 50) / 100
+	_, err := d.readLinesContext(ctx, fmt.Sprintf(format, raw))
+	return err
+}
@@ -0,0 +1,57 @@
+package nanovna
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestDevice_CaptureScreen(t *testing.T) {
+	// Two pixels: pure red and pure green in big-endian RGB565
+	pix := string([]byte{0xf8, 0x00, 0x07, 0xe0})
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"capture": "capture\r\n" + pix + "ch> ",
+	}}
+	dev, _ := Open("COM1", port)
+	dev.hardwareInfo.Screen = ScreenSize{Width: 2, Height: 1}
+
+	img, err := dev.CaptureScreen()
+	if err != nil {
+		t.Fatalf("CaptureScreen failed: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("image bounds = %v, want 2x1", b)
+	}
+	if c := color.RGBAModel.Convert(img.At(0, 0)); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("pixel 0 = %v, want red", c)
+	}
+	if c := color.RGBAModel.Convert(img.At(1, 0)); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("pixel 1 = %v, want green", c)
+	}
+
+	port.Responses["capture"] = shellResponse("capture", "capture?")
+	if _, err := dev.CaptureScreen(); err != ErrUnsupported {
+		t.Errorf("CaptureScreen on firmware without capture = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestDevice_SetBrightness(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"brightness 128": shellResponse("brightness 128"),
+	}}
+	dev, _ := Open("COM1", port)
+	if err := dev.SetBrightness(50); err != ErrUnsupported {
+		t.Errorf("SetBrightness without backlight control = %v, want ErrUnsupported", err)
+	}
+
+	dev.hardwareInfo = getHardwareInfo(VariantVH)
+	dev.hardwareInfo.MaxBrightness = 255
+	if err := dev.SetBrightness(50); err != nil {
+		t.Fatalf("SetBrightness failed: %v", err)
+	}
+	if last := port.Written[len(port.Written)-1]; last != "brightness 128" {
+		t.Errorf("SetBrightness wrote %q, want %q", last, "brightness 128")
+	}
+	if err := dev.SetBrightness(101); err == nil {
+		t.Error("Expected error for brightness above 100")
+	}
+}
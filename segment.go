@@ -0,0 +1,96 @@
+package nanovna
+
+import (
+	"context"
+	"fmt"
+)
+
+// RunSegmentedSweep measures totalPoints linearly spaced points from startHz
+// to stopHz even when that is more than the hardware's MaxSweepPoints, for
+// example 10000 points on a V1 limited to 101. The span is split into
+// consecutive hardware sweeps of at most MaxSweepPoints each, which share
+// their boundary frequency; the device is retuned for each segment and the
+// results are stitched into one SweepData with duplicate boundary points
+// removed. S21, S12 and S22 are kept only when every segment returned them.
+// The sweep configuration in effect beforehand is restored afterwards.
+func (d *Device) RunSegmentedSweep(startHz, stopHz, totalPoints int) (SweepData, error) {
+	return d.RunSegmentedSweepContext(context.Background(), startHz, stopHz, totalPoints)
+}
+
+// RunSegmentedSweepContext is like RunSegmentedSweep but stops waiting for
+// the device once ctx is done.
+func (d *Device) RunSegmentedSweepContext(ctx context.Context, startHz, stopHz, totalPoints int) (SweepData, error) {
+	if startHz >= stopHz {
+		return SweepData{}, fmt.Errorf("start frequency %d Hz must be below stop frequency %d Hz", startHz, stopHz)
+	}
+	if totalPoints < 2 {
+		return SweepData{}, fmt.Errorf("sweep needs at least 2 points, got %d", totalPoints)
+	}
+	fr := d.hardwareInfo.FrequencyRange
+	if float64(startHz) < fr.MinHz || float64(stopHz) > fr.MaxHz {
+		return SweepData{}, fmt.Errorf("range %d-%d Hz is outside %g-%g Hz for %s",
+			startHz, stopHz, fr.MinHz, fr.MaxHz, d.variant.String())
+	}
+	maxPoints := d.hardwareInfo.MaxSweepPoints
+	if maxPoints < 2 {
+		return SweepData{}, fmt.Errorf("%s reports no usable sweep point limit", d.variant.String())
+	}
+
+	if prev, ok := d.GetSweepConfig(); ok {
+		// Leave the device sweeping the previous configuration again
+		defer d.restoreSweepConfig(prev)
+	}
+
+	freqs := SweepConfig{StartHz: startHz, StopHz: stopHz, Points: totalPoints}.Frequencies()
+	var segments []SweepData
+	for first := 0; first < len(freqs)-1; {
+		last := min(first+maxPoints-1, len(freqs)-1)
+		if err := d.SetSweepConfigContext(ctx, freqs[first], freqs[last], last-first+1); err != nil {
+			return SweepData{}, fmt.Errorf("segment %d-%d Hz: %v", freqs[first], freqs[last], err)
+		}
+		seg, err := d.RunSweepContext(ctx)
+		if err != nil {
+			return SweepData{}, fmt.Errorf("segment %d-%d Hz: %v", freqs[first], freqs[last], err)
+		}
+		segments = append(segments, seg)
+		first = last
+	}
+	return stitchSegments(segments), nil
+}
+
+// stitchSegments joins sweeps of consecutive frequency ranges, dropping
+// points that do not lie above the last frequency already stitched, such
+// as the boundary point each segment shares with the one before. A trace
+// other than S11 is kept only when every segment has it in full.
+func stitchSegments(segments []SweepData) SweepData {
+	out := SweepData{Z0: segments[0].Z0}
+	hasS21, hasS12, hasS22 := true, true, true
+	for _, seg := range segments {
+		hasS21 = hasS21 && len(seg.S21) == len(seg.S11)
+		hasS12 = hasS12 && len(seg.S12) == len(seg.S11)
+		hasS22 = hasS22 && len(seg.S22) == len(seg.S11)
+	}
+
+	for _, seg := range segments {
+		for i, f := range seg.Frequencies {
+			if i >= len(seg.S11) {
+				break
+			}
+			if n := len(out.Frequencies); n > 0 && f <= out.Frequencies[n-1]+gridToleranceHz {
+				continue
+			}
+			out.Frequencies = append(out.Frequencies, f)
+			out.S11 = append(out.S11, seg.S11[i])
+			if hasS21 {
+				out.S21 = append(out.S21, seg.S21[i])
+			}
+			if hasS12 {
+				out.S12 = append(out.S12, seg.S12[i])
+			}
+			if hasS22 {
+				out.S22 = append(out.S22, seg.S22[i])
+			}
+		}
+	}
+	return out
+}
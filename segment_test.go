@@ -0,0 +1,63 @@
+package nanovna
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// segmentPort answers "frequencies" and "data 0" for whatever linear sweep
+// was configured last, with S11 equal to the frequency in MHz.
+type segmentPort struct {
+	ScriptedSerialPort
+}
+
+func (s *segmentPort) Write(p []byte) (int, error) {
+	cmd := strings.TrimRight(string(p), "\r\n")
+	var start, stop, points int
+	if n, _ := fmt.Sscanf(cmd, "sweep %d %d %d", &start, &stop, &points); n == 3 {
+		s.Responses[cmd] = shellResponse(cmd)
+		var freqs, data []string
+		for _, f := range (SweepConfig{StartHz: start, StopHz: stop, Points: points}).Frequencies() {
+			freqs = append(freqs, fmt.Sprint(f))
+			data = append(data, fmt.Sprintf("%g 0", float64(f)/1e6))
+		}
+		s.Responses["frequencies"] = shellResponse("frequencies", freqs...)
+		s.Responses["data 0"] = shellResponse("data 0", data...)
+	}
+	return s.ScriptedSerialPort.Write(p)
+}
+
+func TestDevice_RunSegmentedSweep(t *testing.T) {
+	port := &segmentPort{ScriptedSerialPort{Responses: map[string]string{}}}
+	dev, _ := Open("COM1", port)
+	dev.variant = VariantV1
+	dev.hardwareInfo = getHardwareInfo(VariantV1)
+	dev.hardwareInfo.MaxSweepPoints = 3
+	if err := dev.SetSweepConfig(50000000, 60000000, 3); err != nil {
+		t.Fatalf("SetSweepConfig failed: %v", err)
+	}
+
+	data, err := dev.RunSegmentedSweep(1000000, 6000000, 6)
+	if err != nil {
+		t.Fatalf("RunSegmentedSweep failed: %v", err)
+	}
+	if len(data.Frequencies) != 6 || len(data.S11) != 6 || len(data.S21) != 6 {
+		t.Fatalf("got %d/%d/%d points, want 6", len(data.Frequencies), len(data.S11), len(data.S21))
+	}
+	for i, f := range data.Frequencies {
+		if want := float64(1000000 * (i + 1)); f != want || real(data.S11[i]) != want/1e6 {
+			t.Errorf("point %d = %g Hz, %v; want %g Hz", i, f, data.S11[i], want)
+		}
+	}
+	if cfg, _ := dev.GetSweepConfig(); cfg.StartHz != 50000000 || cfg.Points != 3 {
+		t.Errorf("previous configuration not restored, got %+v", cfg)
+	}
+
+	if _, err := dev.RunSegmentedSweep(6000000, 1000000, 6); err == nil {
+		t.Error("expected error for a descending range")
+	}
+	if _, err := dev.RunSegmentedSweep(1000, 6000000, 6); err == nil {
+		t.Error("expected error for a range below the hardware minimum")
+	}
+}
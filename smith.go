@@ -0,0 +1,84 @@
+package nanovna
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/cmplx"
+)
+
+// Smith chart SVG geometry: the unit circle is drawn with radius smithRadius
+// in a square canvas of side smithSize.
+const (
+	smithSize   = 500.0
+	smithRadius = 230.0
+)
+
+// smithGridValues are the normalized resistance and reactance values of the
+// drawn grid circles and arcs.
+var smithGridValues = []float64{0.2, 0.5, 1, 2, 5}
+
+// smithPoint maps a reflection coefficient to SVG canvas coordinates.
+func smithPoint(g complex128) (x, y float64) {
+	c := smithSize / 2
+	return c + smithRadius*real(g), c - smithRadius*imag(g)
+}
+
+// WriteSmithSVG renders a Smith chart with the S11 trace as a standalone SVG
+// document, using only the standard library. The grid shows the unit circle,
+// the real axis, constant-resistance circles and constant-reactance arcs at
+// normalized values 0.2, 0.5, 1, 2 and 5. The trace is drawn in sweep order
+// with the first point marked; reflection coefficients outside the unit
+// circle are clipped to the chart.
+func (d SweepData) WriteSmithSVG(w io.Writer) error {
+	if len(d.S11) == 0 {
+		return errors.New("sweep has no S11 data")
+	}
+
+	c := smithSize / 2
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%g\" height=\"%g\" viewBox=\"0 0 %g %g\">\n",
+		smithSize, smithSize, smithSize, smithSize)
+	fmt.Fprintf(bw, "<defs><clipPath id=\"smith\"><circle cx=\"%g\" cy=\"%g\" r=\"%g\"/></clipPath></defs>\n",
+		c, c, smithRadius)
+	bw.WriteString("<rect width=\"100%\" height=\"100%\" fill=\"white\"/>\n")
+
+	// Grid
+	bw.WriteString("<g fill=\"none\" stroke=\"#bbb\" stroke-width=\"1\" clip-path=\"url(#smith)\">\n")
+	fmt.Fprintf(bw, "<line x1=\"%g\" y1=\"%g\" x2=\"%g\" y2=\"%g\"/>\n", c-smithRadius, c, c+smithRadius, c)
+	for _, r := range smithGridValues {
+		// Constant resistance: centre (r/(1+r), 0), radius 1/(1+r)
+		cx, cy := smithPoint(complex(r/(1+r), 0))
+		fmt.Fprintf(bw, "<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%.2f\"/>\n", cx, cy, smithRadius/(1+r))
+	}
+	for _, x := range smithGridValues {
+		// Constant reactance: centre (1, ±1/x), radius 1/x
+		for _, sign := range []float64{1, -1} {
+			cx, cy := smithPoint(complex(1, sign/x))
+			fmt.Fprintf(bw, "<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%.2f\"/>\n", cx, cy, smithRadius/x)
+		}
+	}
+	bw.WriteString("</g>\n")
+	fmt.Fprintf(bw, "<circle cx=\"%g\" cy=\"%g\" r=\"%g\" fill=\"none\" stroke=\"#444\" stroke-width=\"1.5\"/>\n",
+		c, c, smithRadius)
+
+	// Trace
+	bw.WriteString("<polyline fill=\"none\" stroke=\"#c00\" stroke-width=\"2\" clip-path=\"url(#smith)\" points=\"")
+	for i, g := range d.S11 {
+		if cmplx.IsNaN(g) || cmplx.IsInf(g) {
+			continue
+		}
+		if i > 0 {
+			bw.WriteString(" ")
+		}
+		x, y := smithPoint(g)
+		fmt.Fprintf(bw, "%.2f,%.2f", x, y)
+	}
+	bw.WriteString("\"/>\n")
+	x, y := smithPoint(d.S11[0])
+	fmt.Fprintf(bw, "<circle cx=\"%.2f\" cy=\"%.2f\" r=\"4\" fill=\"#c00\"/>\n", x, y)
+
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,40 @@
+package nanovna
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSweepData_WriteSmithSVG(t *testing.T) {
+	d := SweepData{
+		Frequencies: []float64{1e6, 2e6},
+		S11:         []complex128{0, complex(0, 1)},
+	}
+	var buf bytes.Buffer
+	if err := d.WriteSmithSVG(&buf); err != nil {
+		t.Fatalf("WriteSmithSVG failed: %v", err)
+	}
+
+	// The output must be well-formed XML
+	dec := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("invalid SVG: %v", err)
+			}
+			break
+		}
+	}
+	// Γ = 0 is the chart centre, Γ = j the top of the unit circle
+	if !strings.Contains(buf.String(), `points="250.00,250.00 250.00,20.00"`) {
+		t.Errorf("unexpected trace in:\n%s", buf.String())
+	}
+
+	if err := (SweepData{}).WriteSmithSVG(&buf); err == nil {
+		t.Error("Expected error for empty sweep")
+	}
+}
@@ -0,0 +1,92 @@
+package nanovna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SpectrumData holds a spectrum analyzer trace.
+type SpectrumData struct {
+	Frequencies []float64 // Hz
+	Levels      []float64 // dBm
+}
+
+// RunSpectrum configures a TinySA sweep from startHz to stopHz with points
+// points and reads one frame of it with RunSpectrumSweep. Returns
+// ErrUnsupported on other variants.
+func (d *Device) RunSpectrum(startHz, stopHz, points int) (SpectrumData, error) {
+	return d.RunSpectrumContext(context.Background(), startHz, stopHz, points)
+}
+
+// RunSpectrumContext is like RunSpectrum but stops waiting for the device
+// once ctx is done.
+func (d *Device) RunSpectrumContext(ctx context.Context, startHz, stopHz, points int) (SpectrumData, error) {
+	if d.variant != VariantTinysa {
+		return SpectrumData{}, ErrUnsupported
+	}
+	if err := d.SetSweepConfigContext(ctx, startHz, stopHz, points); err != nil {
+		return SpectrumData{}, err
+	}
+	return d.RunSpectrumSweepContext(ctx)
+}
+
+// RunSpectrumSweep reads one complete spectrum frame of the sweep last
+// configured with SetSweepConfig from a TinySA. The scan free-runs in
+// spectrum mode, so it is paused while the frequencies and levels are read
+// and resumed afterwards; otherwise a read could return a frame torn between
+// two scans. The frame must contain exactly the configured number of points.
+// Returns ErrUnsupported on other variants.
+func (d *Device) RunSpectrumSweep() (SpectrumData, error) {
+	return d.RunSpectrumSweepContext(context.Background())
+}
+
+// RunSpectrumSweepContext is like RunSpectrumSweep but stops waiting for the
+// device once ctx is done.
+func (d *Device) RunSpectrumSweepContext(ctx context.Context) (SpectrumData, error) {
+	if d.variant != VariantTinysa {
+		return SpectrumData{}, ErrUnsupported
+	}
+	cfg, ok := d.GetSweepConfig()
+	if !ok {
+		return SpectrumData{}, errors.New("no sweep configured; call SetSweepConfig first")
+	}
+
+	if err := d.PauseContext(ctx); err != nil {
+		return SpectrumData{}, fmt.Errorf("failed to pause: %v", err)
+	}
+	defer d.Resume()
+
+	var sd SpectrumData
+	freqLines, err := d.readLinesContext(ctx, d.hardwareInfo.CommandSet.FreqCommand)
+	if err != nil {
+		return SpectrumData{}, fmt.Errorf("failed to get frequencies: %v", err)
+	}
+	for _, line := range freqLines {
+		if f, err := strconv.ParseFloat(line, 64); err == nil {
+			sd.Frequencies = append(sd.Frequencies, f)
+		}
+	}
+
+	levelLines, err := d.readLinesContext(ctx, fmt.Sprintf(d.hardwareInfo.CommandSet.DataCommand, 0))
+	if err != nil {
+		return SpectrumData{}, fmt.Errorf("failed to get levels: %v", err)
+	}
+	for _, line := range levelLines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			sd.Levels = append(sd.Levels, v)
+		}
+	}
+
+	if len(sd.Levels) != cfg.Points || len(sd.Frequencies) != cfg.Points {
+		return SpectrumData{}, fmt.Errorf("incomplete frame: %d levels and %d frequencies for %d points",
+			len(sd.Levels), len(sd.Frequencies), cfg.Points)
+	}
+	return sd, nil
+}
@@ -0,0 +1,68 @@
+package nanovna
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDevice_RunSpectrumSweep(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"pause":       shellResponse("pause"),
+		"resume":      shellResponse("resume"),
+		"frequencies": shellResponse("frequencies", "1000000", "2000000"),
+		"data 0":      shellResponse("data 0", "-80.5", "-42.0"),
+	}}
+	dev, _ := Open("COM1", port)
+	if _, err := dev.RunSpectrumSweep(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for non-TinySA, got %v", err)
+	}
+
+	dev.variant = VariantTinysa
+	dev.hardwareInfo = getHardwareInfo(VariantTinysa)
+	dev.sweepConfig = SweepConfig{StartHz: 1000000, StopHz: 2000000, Points: 2}
+	sd, err := dev.RunSpectrumSweep()
+	if err != nil {
+		t.Fatalf("RunSpectrumSweep failed: %v", err)
+	}
+	if len(sd.Levels) != 2 || sd.Levels[1] != -42 || sd.Frequencies[1] != 2e6 {
+		t.Errorf("unexpected spectrum %+v", sd)
+	}
+	if port.Written[0] != "pause" || port.Written[len(port.Written)-1] != "resume" {
+		t.Errorf("frame should be read between pause and resume, got %q", port.Written)
+	}
+
+	dev.sweepConfig.Points = 3
+	if _, err := dev.RunSpectrumSweep(); err == nil {
+		t.Error("Expected error for an incomplete frame")
+	}
+}
+
+func TestDevice_RunSpectrum(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 2000000 2": shellResponse("sweep 1000000 2000000 2"),
+		"pause":                   shellResponse("pause"),
+		"resume":                  shellResponse("resume"),
+		"frequencies":             shellResponse("frequencies", "1000000", "2000000"),
+		"data 0":                  shellResponse("data 0", "-80.5", "-42.0"),
+	}}
+	dev, _ := Open("COM1", port)
+	if _, err := dev.RunSpectrum(1000000, 2000000, 2); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for non-TinySA, got %v", err)
+	}
+	if len(port.Written) != 0 {
+		t.Errorf("nothing should be sent to a non-TinySA, got %q", port.Written)
+	}
+
+	dev.variant = VariantTinysa
+	dev.hardwareInfo = getHardwareInfo(VariantTinysa)
+	sd, err := dev.RunSpectrum(1000000, 2000000, 2)
+	if err != nil {
+		t.Fatalf("RunSpectrum failed: %v", err)
+	}
+	if len(sd.Levels) != 2 || sd.Levels[0] != -80.5 {
+		t.Errorf("unexpected spectrum %+v", sd)
+	}
+	if port.Written[0] != "sweep 1000000 2000000 2" {
+		t.Errorf("sweep should be configured first, got %q", port.Written)
+	}
+}
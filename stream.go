@@ -0,0 +1,147 @@
+package nanovna
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ParseError describes a response row that could not be decoded.
+type ParseError struct {
+	Command string  // Command whose response contained the row
+	Index   int     // Approximate sweep point index of the row
+	FreqHz  float64 // Frequency at Index, or NaN when unknown
+	Line    string  // Raw response line
+	Err     error   // Underlying parse error
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	if math.IsNaN(e.FreqHz) {
+		return fmt.Sprintf("%s: point %d: cannot parse %q: %v", e.Command, e.Index, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: point %d (%.0f Hz): cannot parse %q: %v",
+		e.Command, e.Index, e.FreqHz, e.Line, e.Err)
+}
+
+// Unwrap returns the underlying parse error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// SetStrictParsing selects how streaming sweeps treat malformed rows. When
+// strict, the first bad row aborts the stream with a *ParseError. When
+// tolerant (the default), bad rows are reported to the diagnostic handler
+// and skipped so the rest of the sweep is still delivered.
+func (d *Device) SetStrictParsing(strict bool) {
+	d.strictParsing = strict
+}
+
+// SetDiagnosticHandler registers fn to receive non-fatal diagnostics, such as
+// *ParseError values for rows skipped in tolerant mode. Pass nil to disable.
+func (d *Device) SetDiagnosticHandler(fn func(error)) {
+	d.diagnosticHandler = fn
+}
+
+// diagnose forwards a non-fatal diagnostic to the registered handler.
+func (d *Device) diagnose(err error) {
+	if d.diagnosticHandler != nil {
+		d.diagnosticHandler(err)
+	}
+}
+
+// reportParseError records a malformed row. In strict mode the *ParseError is
+// returned so the caller can abort; otherwise it is passed to the diagnostic
+// handler and nil is returned so the row can be skipped.
+func (d *Device) reportParseError(cmd string, index int, freq float64, line string, err error) error {
+	perr := &ParseError{Command: cmd, Index: index, FreqHz: freq, Line: line, Err: err}
+	if d.strictParsing {
+		return perr
+	}
+	d.diagnose(perr)
+	return nil
+}
+
+// StreamSweep runs a sweep and delivers each measured point to cb in
+// frequency order as its S11 row arrives from the device, rather than after
+// the whole response has been read. Returning false from cb stops the stream
+// early without error. S21 is zero when the hardware does not measure it.
+// cb runs while the device's port is locked, so it must not call methods on
+// the Device.
+//
+// Malformed rows are handled according to SetStrictParsing.
+func (d *Device) StreamSweep(cb func(point int, freq float64, s11, s21 complex128) bool) error {
+	return d.StreamSweepContext(context.Background(), cb)
+}
+
+// StreamSweepContext is like StreamSweep but stops waiting for the device
+// once ctx is done.
+func (d *Device) StreamSweepContext(ctx context.Context, cb func(point int, freq float64, s11, s21 complex128) bool) error {
+	freqCmd := d.hardwareInfo.CommandSet.FreqCommand
+	freqLines, err := d.readLinesContext(ctx, freqCmd)
+	if err != nil {
+		return fmt.Errorf("failed to get frequencies: %v", err)
+	}
+	freqs := make([]float64, len(freqLines))
+	for i, line := range freqLines {
+		freq, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			if perr := d.reportParseError(freqCmd, i, math.NaN(), line, err); perr != nil {
+				return perr
+			}
+			freq = math.NaN()
+		}
+		freqs[i] = freq
+	}
+
+	// S21 is read first so that each S11 row can be delivered with its S21
+	// value as soon as it arrives
+	var s21Cmd string
+	var s21Lines []string
+	if d.hardwareInfo.Capabilities.HasS21 && d.IsPortSupported("S21") {
+		s21Cmd = fmt.Sprintf(d.hardwareInfo.CommandSet.DataCommand, 1)
+		// S21 might not be available; treat it as zero like RunSweep
+		s21Lines, _ = d.readLinesContext(ctx, s21Cmd)
+	}
+
+	s11Cmd := fmt.Sprintf(d.hardwareInfo.CommandSet.DataCommand, 0)
+	var streamErr error
+	i := -1
+	err = d.streamLinesContext(ctx, s11Cmd, func(line string) bool {
+		i++
+		if i >= len(freqs) {
+			return false
+		}
+		freq := freqs[i]
+
+		s11, err := parseComplexLine(line)
+		if err != nil {
+			streamErr = d.reportParseError(s11Cmd, i, freq, line, err)
+			return streamErr == nil
+		}
+
+		var s21 complex128
+		if i < len(s21Lines) {
+			s21, err = parseComplexLine(s21Lines[i])
+			if err != nil {
+				streamErr = d.reportParseError(s21Cmd, i, freq, s21Lines[i], err)
+				return streamErr == nil
+			}
+		}
+
+		// Rows with an unreadable frequency were already reported
+		if math.IsNaN(freq) {
+			return true
+		}
+		return cb(i, freq, s11, s21)
+	})
+	if streamErr != nil {
+		return streamErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get S11 data: %v", err)
+	}
+
+	return nil
+}
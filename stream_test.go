@@ -0,0 +1,104 @@
+package nanovna
+
+import (
+	"errors"
+	"testing"
+)
+
+func newStreamTestDevice(t *testing.T) *Device {
+	t.Helper()
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"frequencies": shellResponse("frequencies", "1000000", "2000000", "3000000"),
+		"data 0":      shellResponse("data 0", "0.1 0.2", "garbage", "0.5 0.6"),
+		"data 1":      shellResponse("data 1", "0.01 0.02", "0.03 0.04", "0.05 0.06"),
+	}}
+	dev, err := Open("COM1", port)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	dev.variant = VariantV1
+	dev.hardwareInfo = getHardwareInfo(VariantV1)
+	return dev
+}
+
+func TestDevice_StreamSweep_TolerantSkipsBadRow(t *testing.T) {
+	dev := newStreamTestDevice(t)
+	var diags []error
+	dev.SetDiagnosticHandler(func(err error) { diags = append(diags, err) })
+
+	var points []int
+	err := dev.StreamSweep(func(point int, freq float64, s11, s21 complex128) bool {
+		points = append(points, point)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("StreamSweep failed: %v", err)
+	}
+	if len(points) != 2 || points[0] != 0 || points[1] != 2 {
+		t.Errorf("delivered points = %v, want [0 2]", points)
+	}
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1", len(diags))
+	}
+	var perr *ParseError
+	if !errors.As(diags[0], &perr) || perr.Index != 1 || perr.FreqHz != 2000000 || perr.Line != "garbage" {
+		t.Errorf("unexpected diagnostic: %v", diags[0])
+	}
+}
+
+func TestDevice_StreamSweep_StrictAborts(t *testing.T) {
+	dev := newStreamTestDevice(t)
+	dev.SetStrictParsing(true)
+
+	calls := 0
+	err := dev.StreamSweep(func(point int, freq float64, s11, s21 complex128) bool {
+		calls++
+		return true
+	})
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Index != 1 {
+		t.Errorf("expected *ParseError at index 1, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("callback called %d times before abort, want 1", calls)
+	}
+}
+
+// slowPort delivers scripted responses a few bytes per read.
+type slowPort struct {
+	ScriptedSerialPort
+	Chunk int
+}
+
+func (s *slowPort) Read(p []byte) (int, error) {
+	if len(p) > s.Chunk {
+		p = p[:s.Chunk]
+	}
+	return s.ScriptedSerialPort.Read(p)
+}
+
+func TestDevice_StreamSweep_Incremental(t *testing.T) {
+	port := &slowPort{ScriptedSerialPort: ScriptedSerialPort{Responses: map[string]string{
+		"frequencies": shellResponse("frequencies", "1000000", "2000000", "3000000"),
+		"data 0":      shellResponse("data 0", "0.1 0.2", "0.3 0.4", "0.5 0.6"),
+		"info":        shellResponse("info", "Board: NanoVNA-H"),
+	}}, Chunk: 8}
+	dev, _ := Open("COM1", port)
+
+	var unread []int
+	err := dev.StreamSweep(func(point int, freq float64, s11, s21 complex128) bool {
+		unread = append(unread, len(port.pending))
+		return point < 1
+	})
+	if err != nil {
+		t.Fatalf("StreamSweep failed: %v", err)
+	}
+	if len(unread) != 2 || unread[0] == 0 {
+		t.Errorf("first point delivered with %v bytes still unread, want delivery before the response ends", unread)
+	}
+
+	// The abandoned rows were drained, so the next command is answered cleanly
+	if raw, err := dev.GetRawInfo(); err != nil || raw != "Board: NanoVNA-H" {
+		t.Errorf("GetRawInfo after early stop = %q, %v", raw, err)
+	}
+}
@@ -0,0 +1,89 @@
+package nanovna
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// SweepMode selects how the points of a sweep are spaced in frequency.
+type SweepMode int
+
+const (
+	SweepLinear SweepMode = iota // Equal steps in Hz, swept by the firmware
+	SweepLog                     // Equal ratios between points, measured point by point
+)
+
+// String returns the string representation of the sweep mode.
+func (m SweepMode) String() string {
+	switch m {
+	case SweepLinear:
+		return "linear"
+	case SweepLog:
+		return "log"
+	default:
+		return "unknown"
+	}
+}
+
+// Frequencies returns the frequency of every point of the sweep in Hz,
+// spaced according to its Mode. The first and last points are exactly
+// StartHz and StopHz.
+func (c SweepConfig) Frequencies() []int {
+	if c.Points <= 0 {
+		return nil
+	}
+	freqs := make([]int, c.Points)
+	for i := range freqs {
+		freqs[i] = c.StartHz
+		if c.Points < 2 || i == 0 {
+			continue
+		}
+		switch c.Mode {
+		case SweepLog:
+			ratio := float64(c.StopHz) / float64(c.StartHz)
+			freqs[i] = int(math.Round(float64(c.StartHz) * math.Pow(ratio, float64(i)/float64(c.Points-1))))
+		default:
+			freqs[i] += int(int64(c.StopHz-c.StartHz) * int64(i) / int64(c.Points-1))
+		}
+	}
+	freqs[c.Points-1] = c.StopHz
+	return freqs
+}
+
+// SetSweepConfigMode is like SetSweepConfig but chooses the point spacing.
+// The firmware only sweeps linearly, so with SweepLog the device is set to
+// the linear sweep over the same range and RunSweep measures the
+// logarithmically spaced points one at a time, which is slower but gives
+// even coverage of a kHz-to-GHz span. SweepConfig.Frequencies on
+// GetSweepConfig lists the resulting points.
+func (d *Device) SetSweepConfigMode(startHz, stopHz int, points int, mode SweepMode) error {
+	return d.SetSweepConfigModeContext(context.Background(), startHz, stopHz, points, mode)
+}
+
+// SetSweepConfigModeContext is like SetSweepConfigMode but stops waiting for
+// the device once ctx is done.
+func (d *Device) SetSweepConfigModeContext(ctx context.Context, startHz, stopHz int, points int, mode SweepMode) error {
+	switch mode {
+	case SweepLinear:
+	case SweepLog:
+		if startHz <= 0 {
+			return fmt.Errorf("logarithmic sweep needs a positive start frequency, got %d Hz", startHz)
+		}
+	default:
+		return fmt.Errorf("unknown sweep mode %d", int(mode))
+	}
+	if err := d.SetSweepConfigContext(ctx, startHz, stopHz, points); err != nil {
+		return err
+	}
+	d.sweepConfig.Mode = mode
+	return nil
+}
+
+// restoreSweepConfig sends cfg back to the device after a temporary
+// reconfiguration and makes it the current configuration again, keeping
+// its Mode, which SetSweepConfig alone would reset to linear.
+func (d *Device) restoreSweepConfig(cfg SweepConfig) {
+	d.SetSweepConfig(cfg.StartHz, cfg.StopHz, cfg.Points)
+	d.sweepConfig = cfg
+}
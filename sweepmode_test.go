@@ -0,0 +1,88 @@
+package nanovna
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSweepConfig_Frequencies(t *testing.T) {
+	tests := []struct {
+		cfg  SweepConfig
+		want []int
+	}{
+		{SweepConfig{StartHz: 1000000, StopHz: 3000000, Points: 3}, []int{1000000, 2000000, 3000000}},
+		{SweepConfig{StartHz: 10000, StopHz: 100000000, Points: 5, Mode: SweepLog}, []int{10000, 100000, 1000000, 10000000, 100000000}},
+		{SweepConfig{StartHz: 5000000, StopHz: 5000000, Points: 1, Mode: SweepLog}, []int{5000000}},
+		{SweepConfig{}, nil},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.Frequencies(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: Frequencies() = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestDevice_SetSweepConfigMode_Log(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 100000000 3": shellResponse("sweep 1000000 100000000 3"),
+		"frequencies":               shellResponse("frequencies", "1000000"),
+		"data 0":                    shellResponse("data 0", "0.1 0.2"),
+	}}
+	for _, f := range []string{"1000000", "10000000", "100000000"} {
+		cmd := "sweep " + f + " " + f + " 1"
+		port.Responses[cmd] = shellResponse(cmd)
+	}
+	dev, _ := Open("COM1", port)
+	if err := dev.SetSweepConfigMode(1000000, 100000000, 3, SweepLog); err != nil {
+		t.Fatalf("SetSweepConfigMode failed: %v", err)
+	}
+	port.Written = nil
+
+	data, err := dev.RunSweep()
+	if err != nil {
+		t.Fatalf("RunSweep failed: %v", err)
+	}
+	if len(data.S11) != 3 {
+		t.Errorf("got %d points, want 3", len(data.S11))
+	}
+	var sweeps []string
+	for _, w := range port.Written {
+		if len(w) > 6 && w[:6] == "sweep " {
+			sweeps = append(sweeps, w)
+		}
+	}
+	want := []string{"sweep 1000000 1000000 1", "sweep 10000000 10000000 1",
+		"sweep 100000000 100000000 1", "sweep 1000000 100000000 3"}
+	if !reflect.DeepEqual(sweeps, want) {
+		t.Errorf("sweep commands = %q, want %q", sweeps, want)
+	}
+	if cfg, _ := dev.GetSweepConfig(); cfg.Mode != SweepLog || cfg.Points != 3 {
+		t.Errorf("configuration after sweep = %+v, want the log sweep", cfg)
+	}
+
+	if err := dev.SetSweepConfigMode(0, 100000000, 3, SweepLog); err == nil {
+		t.Error("expected error for a log sweep from 0 Hz")
+	}
+	if err := dev.SetSweepConfigMode(1000000, 100000000, 3, SweepMode(7)); err == nil {
+		t.Error("expected error for an unknown mode")
+	}
+}
+
+func TestDevice_MeasurePoint_KeepsLogMode(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 100000000 3": shellResponse("sweep 1000000 100000000 3"),
+		"sweep 5000000 5000000 1":   shellResponse("sweep 5000000 5000000 1"),
+		"frequencies":               shellResponse("frequencies", "5000000"),
+		"data 0":                    shellResponse("data 0", "0.1 0.2"),
+	}}
+	dev, _ := Open("COM1", port)
+	if err := dev.SetSweepConfigMode(1000000, 100000000, 3, SweepLog); err != nil {
+		t.Fatalf("SetSweepConfigMode failed: %v", err)
+	}
+	if _, _, err := dev.MeasurePoint(5000000); err != nil {
+		t.Fatalf("MeasurePoint failed: %v", err)
+	}
+	if cfg, _ := dev.GetSweepConfig(); cfg.Mode != SweepLog || cfg.Points != 3 {
+		t.Errorf("configuration after MeasurePoint = %+v, want the log sweep", cfg)
+	}
+}
@@ -0,0 +1,108 @@
+package nanovna
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// speedOfLight is the propagation speed in vacuum, in m/s.
+const speedOfLight = 299792458.0
+
+// tdrPadFactor is how many times longer than the (power-of-two rounded)
+// sweep the inverse FFT is; zero padding interpolates the time axis.
+const tdrPadFactor = 8
+
+// TDRResult is a time-domain reflectometry trace computed from S11.
+// Distance is the one-way cable length to each sample in meters; Impulse
+// is the impulse response magnitude (a lone discontinuity with reflection
+// coefficient Γ peaks at |Γ|) and Step the running sum of the real impulse
+// response.
+type TDRResult struct {
+	Distance []float64
+	Impulse  []float64
+	Step     []float64
+}
+
+// TDR transforms the S11 frequency response into an impulse and step
+// response versus distance, the time-domain view offered by variants with
+// HasTimeDomain. velocityFactor is the cable's propagation speed relative
+// to light (0.66 for solid PE coax) and must lie in (0, 1]. The sweep must
+// be ascending and uniformly spaced; the unambiguous range is
+// c·velocityFactor/(2·step). S11 is tapered across the band with window
+// before the inverse FFT: WindowRectangular resolves the closest
+// discontinuities, WindowBlackman the weakest ones next to a strong one.
+func (d SweepData) TDR(velocityFactor float64, window Window) (TDRResult, error) {
+	n := len(d.S11)
+	if n != len(d.Frequencies) {
+		return TDRResult{}, errors.New("frequency and S11 lengths differ")
+	}
+	if n < 2 {
+		return TDRResult{}, fmt.Errorf("TDR needs at least 2 points, got %d", n)
+	}
+	if velocityFactor <= 0 || velocityFactor > 1 {
+		return TDRResult{}, fmt.Errorf("velocity factor %g out of range (0, 1]", velocityFactor)
+	}
+	step := (d.Frequencies[n-1] - d.Frequencies[0]) / float64(n-1)
+	for i, f := range d.Frequencies {
+		if step <= 0 || math.Abs(f-(d.Frequencies[0]+float64(i)*step)) > gridToleranceHz {
+			return TDRResult{}, errors.New("TDR needs an ascending, uniformly spaced sweep")
+		}
+	}
+
+	size := (1 << bits.Len(uint(n-1))) * tdrPadFactor
+	buf := make([]complex128, size)
+	var gain float64
+	for k, g := range d.S11 {
+		w := window.at(float64(k) / float64(n-1))
+		buf[k] = g * complex(w, 0)
+		gain += w
+	}
+	fft(buf, true)
+
+	dt := 1 / (float64(size) * step)
+	res := TDRResult{
+		Distance: make([]float64, size),
+		Impulse:  make([]float64, size),
+		Step:     make([]float64, size),
+	}
+	var sum float64
+	for i, h := range buf {
+		h /= complex(gain, 0)
+		sum += real(h)
+		res.Distance[i] = speedOfLight * velocityFactor * float64(i) * dt / 2
+		res.Impulse[i] = cmplx.Abs(h)
+		res.Step[i] = sum
+	}
+	return res, nil
+}
+
+// fft transforms x in place with an unnormalized iterative radix-2 FFT;
+// inverse selects the positive exponent. len(x) must be a power of two.
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	shift := 64 - uint(bits.Len(uint(n-1)))
+	for i := range x {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if n > 1 && i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			tw := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*tw
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				tw *= w
+			}
+		}
+	}
+}
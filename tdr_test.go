@@ -0,0 +1,62 @@
+package nanovna
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestFFT_RoundTrip(t *testing.T) {
+	x := []complex128{1, 2i, -3, 4 + 1i, 0, 0.5, -1i, 2}
+	y := append([]complex128(nil), x...)
+	fft(y, false)
+	fft(y, true)
+	for i := range x {
+		if cmplx.Abs(y[i]/complex(float64(len(x)), 0)-x[i]) > 1e-12 {
+			t.Fatalf("round trip [%d] = %v, want %v", i, y[i]/8, x[i])
+		}
+	}
+}
+
+func TestSweepData_TDR(t *testing.T) {
+	const (
+		vf     = 0.66
+		length = 5.0
+		points = 101
+	)
+	tau := 2 * length / (speedOfLight * vf)
+	d := SweepData{}
+	for i := 0; i < points; i++ {
+		f := 1e6 + float64(i)*9e6
+		d.Frequencies = append(d.Frequencies, f)
+		d.S11 = append(d.S11, cmplx.Rect(0.5, math.Pi-2*math.Pi*f*tau))
+	}
+
+	res, err := d.TDR(vf, WindowHann)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Distance) != len(res.Impulse) || len(res.Step) != len(res.Impulse) {
+		t.Fatalf("mismatched result lengths %d/%d/%d", len(res.Distance), len(res.Impulse), len(res.Step))
+	}
+	peak := 0
+	for i, v := range res.Impulse {
+		if v > res.Impulse[peak] {
+			peak = i
+		}
+	}
+	if math.Abs(res.Distance[peak]-length) > 0.05 {
+		t.Errorf("peak at %.3f m, want %.1f m", res.Distance[peak], length)
+	}
+	if math.Abs(res.Impulse[peak]-0.5) > 0.02 {
+		t.Errorf("peak magnitude %.3f, want 0.5", res.Impulse[peak])
+	}
+
+	if _, err := d.TDR(0, WindowHann); err == nil {
+		t.Error("expected error for zero velocity factor")
+	}
+	d.Frequencies[3] += 1e5
+	if _, err := d.TDR(vf, WindowHann); err == nil {
+		t.Error("expected error for non-uniform grid")
+	}
+}
@@ -0,0 +1,305 @@
+package nanovna
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TouchstoneFormat selects how complex values are written in a Touchstone file.
+type TouchstoneFormat int
+
+const (
+	TouchstoneRI TouchstoneFormat = iota // Real and imaginary parts
+	TouchstoneMA                         // Linear magnitude and angle in degrees
+	TouchstoneDB                         // Magnitude in dB and angle in degrees
+)
+
+// String returns the option-line keyword for the format.
+func (tf TouchstoneFormat) String() string {
+	switch tf {
+	case TouchstoneRI:
+		return "RI"
+	case TouchstoneMA:
+		return "MA"
+	case TouchstoneDB:
+		return "DB"
+	default:
+		return "Unknown"
+	}
+}
+
+// touchstoneFile is the in-memory form of a Touchstone 1.0 file.
+type touchstoneFile struct {
+	Ports       int      // 1 or 2
+	Z0          float64  // Reference impedance in ohms
+	Comments    []string // Comment lines without the leading "!"
+	Frequencies []float64
+	// S holds one slice per parameter in file column order: S11 for
+	// one-port files, S11, S21, S12, S22 for two-port files.
+	S [][]complex128
+}
+
+// readTouchstone parses a one- or two-port Touchstone 1.0 file. The port
+// count is taken from the number of values on the first data line.
+func readTouchstone(r io.Reader) (touchstoneFile, error) {
+	tf := touchstoneFile{Z0: DefaultZ0}
+	freqScale := 1e9 // Touchstone default unit is GHz
+	format := TouchstoneMA
+	sawOptions := false
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "!") {
+			tf.Comments = append(tf.Comments, strings.TrimPrefix(line, "!"))
+			continue
+		}
+		if i := strings.Index(line, "!"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			if sawOptions {
+				return tf, fmt.Errorf("line %d: duplicate option line", lineNum)
+			}
+			sawOptions = true
+			var err error
+			freqScale, format, tf.Z0, err = parseTouchstoneOptions(line)
+			if err != nil {
+				return tf, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if tf.Ports == 0 {
+			switch len(fields) {
+			case 3:
+				tf.Ports = 1
+			case 9:
+				tf.Ports = 2
+			default:
+				return tf, fmt.Errorf("line %d: expected 3 or 9 values, got %d", lineNum, len(fields))
+			}
+			tf.S = make([][]complex128, tf.Ports*tf.Ports)
+		}
+		if len(fields) != 1+2*len(tf.S) {
+			return tf, fmt.Errorf("line %d: expected %d values, got %d", lineNum, 1+2*len(tf.S), len(fields))
+		}
+
+		values := make([]float64, len(fields))
+		for i, f := range fields {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return tf, fmt.Errorf("line %d: invalid number %q", lineNum, f)
+			}
+			values[i] = v
+		}
+		tf.Frequencies = append(tf.Frequencies, values[0]*freqScale)
+		for k := range tf.S {
+			tf.S[k] = append(tf.S[k], fromTouchstonePair(values[1+2*k], values[2+2*k], format))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return tf, err
+	}
+	if tf.Ports == 0 {
+		return tf, fmt.Errorf("no data lines found")
+	}
+	return tf, nil
+}
+
+// parseTouchstoneOptions parses a "# <unit> S <format> R <z0>" option line.
+// Keywords may appear in any order; omitted ones keep the Touchstone
+// defaults of GHz, MA and 50 ohms.
+func parseTouchstoneOptions(line string) (freqScale float64, format TouchstoneFormat, z0 float64, err error) {
+	freqScale, format, z0 = 1e9, TouchstoneMA, DefaultZ0
+	fields := strings.Fields(strings.TrimPrefix(line, "#"))
+	for i := 0; i < len(fields); i++ {
+		switch strings.ToUpper(fields[i]) {
+		case "HZ":
+			freqScale = 1
+		case "KHZ":
+			freqScale = 1e3
+		case "MHZ":
+			freqScale = 1e6
+		case "GHZ":
+			freqScale = 1e9
+		case "S":
+		case "Y", "Z", "H", "G":
+			return 0, 0, 0, fmt.Errorf("unsupported parameter type %q", fields[i])
+		case "RI":
+			format = TouchstoneRI
+		case "MA":
+			format = TouchstoneMA
+		case "DB":
+			format = TouchstoneDB
+		case "R":
+			if i+1 >= len(fields) {
+				return 0, 0, 0, fmt.Errorf("missing reference impedance after R")
+			}
+			i++
+			z0, err = strconv.ParseFloat(fields[i], 64)
+			if err != nil || z0 <= 0 {
+				return 0, 0, 0, fmt.Errorf("invalid reference impedance %q", fields[i])
+			}
+		default:
+			return 0, 0, 0, fmt.Errorf("unknown option %q", fields[i])
+		}
+	}
+	return freqScale, format, z0, nil
+}
+
+// writeTouchstone writes tf with frequencies in Hz using the given format.
+func writeTouchstone(w io.Writer, tf touchstoneFile, format TouchstoneFormat) error {
+	if format != TouchstoneRI && format != TouchstoneMA && format != TouchstoneDB {
+		return fmt.Errorf("unknown Touchstone format %d", format)
+	}
+	for k, s := range tf.S {
+		if len(s) != len(tf.Frequencies) {
+			return fmt.Errorf("parameter %d has %d values for %d frequencies", k, len(s), len(tf.Frequencies))
+		}
+	}
+	z0 := tf.Z0
+	if z0 <= 0 {
+		z0 = DefaultZ0
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, c := range tf.Comments {
+		fmt.Fprintf(bw, "!%s\n", c)
+	}
+	fmt.Fprintf(bw, "# HZ S %s R %s\n", format, strconv.FormatFloat(z0, 'g', -1, 64))
+	for i, f := range tf.Frequencies {
+		bw.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+		for _, s := range tf.S {
+			a, b := toTouchstonePair(s[i], format)
+			fmt.Fprintf(bw, " %s %s", strconv.FormatFloat(a, 'g', -1, 64), strconv.FormatFloat(b, 'g', -1, 64))
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// toTouchstonePair converts v to the two columns of the given format.
+func toTouchstonePair(v complex128, format TouchstoneFormat) (float64, float64) {
+	switch format {
+	case TouchstoneMA:
+		return cmplx.Abs(v), phaseDegrees(v)
+	case TouchstoneDB:
+		return magnitudeDB(v), phaseDegrees(v)
+	default:
+		return real(v), imag(v)
+	}
+}
+
+// fromTouchstonePair converts two columns of the given format to a complex value.
+func fromTouchstonePair(a, b float64, format TouchstoneFormat) complex128 {
+	switch format {
+	case TouchstoneMA:
+		return cmplx.Rect(a, b*math.Pi/180)
+	case TouchstoneDB:
+		return cmplx.Rect(math.Pow(10, a/20), b*math.Pi/180)
+	default:
+		return complex(a, b)
+	}
+}
+
+// ConvertTouchstone reads a one- or two-port Touchstone file in any format
+// and frequency unit and writes it to w in toFormat, with frequencies in Hz.
+// Comment lines and the reference impedance are preserved.
+func ConvertTouchstone(r io.Reader, w io.Writer, toFormat TouchstoneFormat) error {
+	tf, err := readTouchstone(r)
+	if err != nil {
+		return err
+	}
+	return writeTouchstone(w, tf, toFormat)
+}
+
+// WriteTouchstoneS1P writes the sweep's S11 as a one-port Touchstone 1.0
+// file with frequencies in Hz, rows in ascending frequency order, and the
+// sweep's reference impedance on the option line.
+func (data SweepData) WriteTouchstoneS1P(w io.Writer, format TouchstoneFormat) error {
+	if len(data.S11) != len(data.Frequencies) {
+		return fmt.Errorf("S11 has %d values for %d frequencies", len(data.S11), len(data.Frequencies))
+	}
+	if !sort.Float64sAreSorted(data.Frequencies) {
+		data = data.SortByFrequency()
+	}
+	return writeTouchstone(w, touchstoneFile{
+		Ports:       1,
+		Z0:          data.referenceZ0(),
+		Frequencies: data.Frequencies,
+		S:           [][]complex128{data.S11},
+	}, format)
+}
+
+// WriteTouchstoneS2P writes the sweep as a two-port Touchstone 1.0 file in
+// the canonical S11 S21 S12 S22 column order, with frequencies in Hz and
+// rows in ascending frequency order. S12 and S22 are written when present
+// (V2 Plus4); otherwise the hardware measured only the forward direction and
+// those columns are written as zeros.
+func (data SweepData) WriteTouchstoneS2P(w io.Writer, format TouchstoneFormat) error {
+	n := len(data.Frequencies)
+	if len(data.S11) != n || len(data.S21) != n {
+		return fmt.Errorf("S11 and S21 have %d and %d values for %d frequencies", len(data.S11), len(data.S21), n)
+	}
+	for _, p := range []struct {
+		name string
+		v    []complex128
+	}{{"S12", data.S12}, {"S22", data.S22}} {
+		if p.v != nil && len(p.v) != n {
+			return fmt.Errorf("%s has %d values for %d frequencies", p.name, len(p.v), n)
+		}
+	}
+	if !sort.Float64sAreSorted(data.Frequencies) {
+		data = data.SortByFrequency()
+	}
+	s12, s22 := data.S12, data.S22
+	// Sorting drops duplicate frequencies, so size the zeros afresh
+	if s12 == nil {
+		s12 = make([]complex128, len(data.Frequencies))
+	}
+	if s22 == nil {
+		s22 = make([]complex128, len(data.Frequencies))
+	}
+	return writeTouchstone(w, touchstoneFile{
+		Ports:       2,
+		Z0:          data.referenceZ0(),
+		Frequencies: data.Frequencies,
+		S:           [][]complex128{data.S11, data.S21, s12, s22},
+	}, format)
+}
+
+// ReadTouchstone parses a one- or two-port Touchstone 1.0 file into
+// SweepData, converting frequencies to Hz and values to complex form
+// according to the option line. The reference impedance is kept in Z0.
+// Two-port files fill S21, S12 and S22 as well.
+func ReadTouchstone(r io.Reader) (SweepData, error) {
+	tf, err := readTouchstone(r)
+	if err != nil {
+		return SweepData{}, err
+	}
+	data := SweepData{
+		Frequencies: tf.Frequencies,
+		S11:         tf.S[0],
+		Z0:          tf.Z0,
+	}
+	if tf.Ports == 2 {
+		data.S21 = tf.S[1]
+		data.S12 = tf.S[2]
+		data.S22 = tf.S[3]
+	}
+	return data, nil
+}
@@ -0,0 +1,162 @@
+package nanovna
+
+import (
+	"bytes"
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+const sampleS1P = `! Measured on bench
+# MHZ S RI R 75
+1.0 0.5 -0.25
+2.0 0.1 0.2 ! trailing comment
+`
+
+func TestConvertTouchstone_RoundTrip(t *testing.T) {
+	var db bytes.Buffer
+	if err := ConvertTouchstone(strings.NewReader(sampleS1P), &db, TouchstoneDB); err != nil {
+		t.Fatalf("ConvertTouchstone to DB failed: %v", err)
+	}
+	if !strings.Contains(db.String(), "# HZ S DB R 75") || !strings.Contains(db.String(), "! Measured on bench") {
+		t.Errorf("unexpected DB output:\n%s", db.String())
+	}
+
+	var ri bytes.Buffer
+	if err := ConvertTouchstone(&db, &ri, TouchstoneRI); err != nil {
+		t.Fatalf("ConvertTouchstone to RI failed: %v", err)
+	}
+	tf, err := readTouchstone(&ri)
+	if err != nil {
+		t.Fatalf("readTouchstone failed: %v", err)
+	}
+	if tf.Ports != 1 || tf.Z0 != 75 || len(tf.Frequencies) != 2 || tf.Frequencies[1] != 2e6 {
+		t.Fatalf("unexpected file after round trip: %+v", tf)
+	}
+	if cmplx.Abs(tf.S[0][0]-complex(0.5, -0.25)) > 1e-12 {
+		t.Errorf("S11[0] = %v, want (0.5-0.25i)", tf.S[0][0])
+	}
+}
+
+func TestReadTouchstone_Malformed(t *testing.T) {
+	tests := map[string]string{
+		"bad option":  "# MHZ S XX R 50\n1 0 0\n",
+		"ragged rows": "# HZ S RI R 50\n1 0 0\n2 0\n",
+		"no data":     "! only a comment\n",
+	}
+	for name, in := range tests {
+		if _, err := readTouchstone(strings.NewReader(in)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestSweepData_WriteTouchstoneS1P(t *testing.T) {
+	data := SweepData{
+		Frequencies: []float64{2e6, 1e6},
+		S11:         []complex128{complex(0.1, 0.2), complex(0.5, -0.25)},
+	}
+	var buf bytes.Buffer
+	if err := data.WriteTouchstoneS1P(&buf, TouchstoneRI); err != nil {
+		t.Fatalf("WriteTouchstoneS1P failed: %v", err)
+	}
+	want := "# HZ S RI R 50\n1000000 0.5 -0.25\n2000000 0.1 0.2\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := data.WriteTouchstoneS1P(&buf, TouchstoneMA); err != nil {
+		t.Fatalf("WriteTouchstoneS1P MA failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "# HZ S MA R 50\n") {
+		t.Errorf("unexpected MA option line:\n%s", buf.String())
+	}
+
+	data.S11 = data.S11[:1]
+	buf.Reset()
+	if err := data.WriteTouchstoneS1P(&buf, TouchstoneRI); err == nil || buf.Len() != 0 {
+		t.Error("Expected error and no output for mismatched lengths")
+	}
+}
+
+func TestSweepData_WriteTouchstoneS2P(t *testing.T) {
+	data := SweepData{
+		Frequencies: []float64{1e6},
+		S11:         []complex128{complex(0.5, -0.25)},
+		S21:         []complex128{complex(0.1, 0.2)},
+		Z0:          75,
+	}
+	var buf bytes.Buffer
+	if err := data.WriteTouchstoneS2P(&buf, TouchstoneRI); err != nil {
+		t.Fatalf("WriteTouchstoneS2P failed: %v", err)
+	}
+	want := "# HZ S RI R 75\n1000000 0.5 -0.25 0.1 0.2 0 0 0 0\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	tf, err := readTouchstone(&buf)
+	if err != nil || tf.Ports != 2 {
+		t.Fatalf("output did not parse as two-port: %+v, %v", tf, err)
+	}
+
+	data.S12 = []complex128{complex(0.3, 0)}
+	data.S22 = []complex128{complex(0, 0.4)}
+	buf.Reset()
+	if err := data.WriteTouchstoneS2P(&buf, TouchstoneRI); err != nil {
+		t.Fatalf("WriteTouchstoneS2P with S12/S22 failed: %v", err)
+	}
+	if want := "# HZ S RI R 75\n1000000 0.5 -0.25 0.1 0.2 0.3 0 0 0.4\n"; buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	back, err := ReadTouchstone(&buf)
+	if err != nil || len(back.S22) != 1 || back.S22[0] != complex(0, 0.4) {
+		t.Errorf("S22 did not round-trip: %+v, %v", back, err)
+	}
+
+	unsorted := SweepData{
+		Frequencies: []float64{2e6, 1e6, 2e6},
+		S11:         []complex128{0.2, 0.1, 0.3},
+		S21:         []complex128{0.5, 0.4, 0.6},
+	}
+	buf.Reset()
+	if err := unsorted.WriteTouchstoneS2P(&buf, TouchstoneRI); err != nil {
+		t.Fatalf("WriteTouchstoneS2P with a duplicated, unsorted grid failed: %v", err)
+	}
+	if want := "# HZ S RI R 50\n1000000 0.1 0 0.4 0 0 0 0 0\n2000000 0.2 0 0.5 0 0 0 0 0\n"; buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	data.S22 = []complex128{}
+	if err := data.WriteTouchstoneS2P(&buf, TouchstoneRI); err == nil {
+		t.Error("Expected error for S22 length mismatch")
+	}
+	data.S21 = nil
+	if err := data.WriteTouchstoneS2P(&buf, TouchstoneRI); err == nil {
+		t.Error("Expected error for missing S21")
+	}
+}
+
+func TestReadTouchstone(t *testing.T) {
+	data, err := ReadTouchstone(strings.NewReader(sampleS1P))
+	if err != nil {
+		t.Fatalf("ReadTouchstone failed: %v", err)
+	}
+	if len(data.Frequencies) != 2 || data.Frequencies[0] != 1e6 || data.Z0 != 75 || data.S21 != nil {
+		t.Errorf("unexpected one-port data: %+v", data)
+	}
+
+	s2p := "# GHZ S DB R 50\n0.001 0 180 -20 0 0 0 0 0\n"
+	data, err = ReadTouchstone(strings.NewReader(s2p))
+	if err != nil {
+		t.Fatalf("ReadTouchstone s2p failed: %v", err)
+	}
+	if data.Frequencies[0] != 1e6 || cmplx.Abs(data.S11[0]+1) > 1e-12 || cmplx.Abs(data.S21[0]-0.1) > 1e-12 {
+		t.Errorf("unexpected two-port data: %+v", data)
+	}
+
+	if _, err := ReadTouchstone(strings.NewReader("# HZ S RI R\n1 0 0\n")); err == nil {
+		t.Error("Expected error for malformed option line")
+	}
+}
@@ -0,0 +1,112 @@
+package nanovna
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+// Transport is the byte stream a Device communicates over: a USB serial
+// port, a TCP connection to a serial bridge, a Bluetooth RFCOMM socket and
+// so on. A Read that finds no data within the transport's read timeout
+// should return an error whose message contains "timeout".
+type Transport interface {
+	Write([]byte) (int, error)
+	Read([]byte) (int, error)
+	Close() error
+}
+
+// ReadDeadliner is optionally implemented by transports whose reads can be
+// bounded by an absolute deadline, such as net.Conn. Transaction uses it so
+// that a blocked read cannot outlast the transaction timeout.
+type ReadDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// SerialPort is the former name of Transport.
+//
+// Deprecated: use Transport.
+type SerialPort = Transport
+
+// NewSerialTransport opens a serial port with the tarm/serial package and
+// returns it as a Transport.
+func NewSerialTransport(c *serial.Config) (Transport, error) {
+	p, err := serial.OpenPort(c)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// netTransport adapts a net.Conn to the timeout semantics of a serial port.
+type netTransport struct {
+	conn        net.Conn
+	readTimeout time.Duration
+	deadline    time.Time // Caller deadline from SetReadDeadline, zero if none
+}
+
+// NewNetTransport wraps conn as a Transport. Each Read waits at most
+// readTimeout for data, matching the read timeout of a serial port, so the
+// text protocol can detect the end of a response. The returned transport
+// also implements ReadDeadliner.
+func NewNetTransport(conn net.Conn, readTimeout time.Duration) Transport {
+	return &netTransport{conn: conn, readTimeout: readTimeout}
+}
+
+// Read implements Transport.
+func (t *netTransport) Read(p []byte) (int, error) {
+	deadline := t.deadline
+	if t.readTimeout > 0 {
+		if limit := time.Now().Add(t.readTimeout); deadline.IsZero() || limit.Before(deadline) {
+			deadline = limit
+		}
+	}
+	if err := t.conn.SetReadDeadline(deadline); err != nil {
+		return 0, err
+	}
+	return t.conn.Read(p)
+}
+
+// Write implements Transport.
+func (t *netTransport) Write(p []byte) (int, error) {
+	return t.conn.Write(p)
+}
+
+// Close implements Transport.
+func (t *netTransport) Close() error {
+	return t.conn.Close()
+}
+
+// SetReadDeadline implements ReadDeadliner. Reads end at the earlier of the
+// deadline and their own timeout; a zero deadline removes it.
+func (t *netTransport) SetReadDeadline(deadline time.Time) error {
+	t.deadline = deadline
+	return nil
+}
+
+// tcpReadTimeout is the per-read timeout of transports opened by OpenTCP.
+const tcpReadTimeout = time.Second
+
+// OpenTCP connects to a NanoVNA exposed over TCP, for example through a
+// serial-to-network bridge, at addr ("host:port").
+func OpenTCP(addr string) (*Device, error) {
+	dial := func() (Transport, error) {
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %v", addr, err)
+		}
+		return NewNetTransport(conn, tcpReadTimeout), nil
+	}
+	t, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	d, err := Open(addr, t)
+	if err != nil {
+		return nil, err
+	}
+	d.reopen = dial
+	return d, nil
+}
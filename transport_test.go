@@ -0,0 +1,41 @@
+package nanovna
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNetTransport(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 64)
+		n, _ := server.Read(buf)
+		if strings.TrimSpace(string(buf[:n])) == "version" {
+			server.Write([]byte(shellResponse("version", "1.2.00")))
+		}
+	}()
+
+	dev, _ := Open("tcp", NewNetTransport(client, 50*time.Millisecond))
+	if _, ok := dev.GetPortHandle().(ReadDeadliner); !ok {
+		t.Error("net transport should implement ReadDeadliner")
+	}
+	resp, err := dev.Transaction([]byte("version\r"), func(b []byte) bool {
+		return strings.Contains(string(b), "ch>")
+	}, time.Second)
+	if err != nil || !strings.Contains(string(resp), "1.2.00") {
+		t.Fatalf("Transaction() = %q, %v", resp, err)
+	}
+
+	// A read with no data must time out rather than block
+	start := time.Now()
+	if _, err := dev.GetPortHandle().Read(make([]byte, 8)); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("read blocked past its timeout")
+	}
+	dev.Close()
+}
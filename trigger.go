@@ -0,0 +1,105 @@
+package nanovna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Pause stops the device's free-running sweep so that its measurement
+// buffers stay stable while they are read.
+func (d *Device) Pause() error {
+	return d.PauseContext(context.Background())
+}
+
+// PauseContext is like Pause but stops waiting for the device once ctx is
+// done.
+func (d *Device) PauseContext(ctx context.Context) error {
+	_, err := d.readLinesContext(ctx, "pause")
+	return err
+}
+
+// Resume restarts free-running sweeps after Pause.
+func (d *Device) Resume() error {
+	return d.ResumeContext(context.Background())
+}
+
+// ResumeContext is like Resume but stops waiting for the device once ctx is
+// done.
+func (d *Device) ResumeContext(ctx context.Context) error {
+	_, err := d.readLinesContext(ctx, "resume")
+	return err
+}
+
+// WaitSettle waits until the firmware shell answers a bare terminator with
+// its prompt, which it only does once a previously triggered sweep has
+// completed. It returns an error if the prompt does not appear within timeout.
+func (d *Device) WaitSettle(timeout time.Duration) error {
+	return d.WaitSettleContext(context.Background(), timeout)
+}
+
+// WaitSettleContext is like WaitSettle but stops waiting for the device once
+// ctx is done.
+func (d *Device) WaitSettleContext(ctx context.Context, timeout time.Duration) error {
+	if d.GetPortHandle() == nil {
+		return errors.New("device not open")
+	}
+
+	prompt := d.prompt()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		resp, err := d.Transaction([]byte(d.commandTerminator()), func(b []byte) bool {
+			return strings.Contains(string(b), prompt)
+		}, settlePollInterval)
+		if strings.Contains(string(resp), prompt) {
+			return nil
+		}
+		if err != nil && !strings.Contains(err.Error(), "timed out") {
+			return err
+		}
+	}
+	return fmt.Errorf("device did not settle within %v", timeout)
+}
+
+// settlePollInterval is how long WaitSettle waits for a prompt after each
+// bare terminator it sends.
+const settlePollInterval = 50 * time.Millisecond
+
+// singleTriggerTimeout bounds how long RunSingleTriggered waits for the
+// triggered sweep to complete.
+const singleTriggerTimeout = 30 * time.Second
+
+// RunSingleTriggered performs one coherent sweep of the configuration last
+// applied with SetSweepConfig: it pauses free-running sweeps, triggers a
+// single sweep with the firmware `scan` command, waits for it to complete
+// with WaitSettle and then reads the data channels. This guarantees the data
+// comes from one complete sweep rather than a buffer updated mid-read, which
+// is what calibration-grade measurements need. The device is left paused;
+// call Resume to restart free-running sweeps.
+func (d *Device) RunSingleTriggered() (SweepData, error) {
+	return d.RunSingleTriggeredContext(context.Background())
+}
+
+// RunSingleTriggeredContext is like RunSingleTriggered but stops waiting for
+// the device once ctx is done.
+func (d *Device) RunSingleTriggeredContext(ctx context.Context) (SweepData, error) {
+	cfg, ok := d.GetSweepConfig()
+	if !ok {
+		return SweepData{}, errors.New("no sweep configured; call SetSweepConfig first")
+	}
+	if err := d.PauseContext(ctx); err != nil {
+		return SweepData{}, fmt.Errorf("failed to pause: %v", err)
+	}
+	if _, err := d.sendCommandContext(ctx, fmt.Sprintf("scan %d %d %d", cfg.StartHz, cfg.StopHz, cfg.Points)); err != nil {
+		return SweepData{}, fmt.Errorf("failed to trigger sweep: %v", err)
+	}
+	if err := d.WaitSettleContext(ctx, singleTriggerTimeout); err != nil {
+		return SweepData{}, err
+	}
+	return d.RunSweepContext(ctx)
+}
@@ -0,0 +1,38 @@
+package nanovna
+
+import "testing"
+
+func TestDevice_RunSingleTriggered(t *testing.T) {
+	port := &ScriptedSerialPort{Responses: map[string]string{
+		"sweep 1000000 2000000 2": shellResponse("sweep 1000000 2000000 2"),
+		"pause":                   shellResponse("pause"),
+		"scan 1000000 2000000 2":  shellResponse("scan 1000000 2000000 2"),
+		"":                        "ch> ",
+		"frequencies":             shellResponse("frequencies", "1000000", "2000000"),
+		"data 0":                  shellResponse("data 0", "0.1 0.2", "0.3 0.4"),
+	}}
+	dev, _ := Open("COM1", port)
+	if _, err := dev.RunSingleTriggered(); err == nil {
+		t.Error("Expected error without a sweep configuration")
+	}
+
+	if err := dev.SetSweepConfig(1000000, 2000000, 2); err != nil {
+		t.Fatalf("SetSweepConfig failed: %v", err)
+	}
+	data, err := dev.RunSingleTriggered()
+	if err != nil {
+		t.Fatalf("RunSingleTriggered failed: %v", err)
+	}
+	if len(data.S11) != 2 {
+		t.Errorf("got %d points, want 2", len(data.S11))
+	}
+	want := []string{"sweep 1000000 2000000 2", "pause", "scan 1000000 2000000 2", "", "frequencies", "data 0"}
+	if len(port.Written) != len(want) {
+		t.Fatalf("commands = %q, want %q", port.Written, want)
+	}
+	for i := range want {
+		if port.Written[i] != want[i] {
+			t.Errorf("command %d = %q, want %q", i, port.Written[i], want[i])
+		}
+	}
+}
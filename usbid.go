@@ -0,0 +1,63 @@
+package nanovna
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// USBID is a USB vendor and product ID pair.
+type USBID struct {
+	VID uint16
+	PID uint16
+}
+
+// String returns the ID in the usual "vvvv:pppp" hexadecimal form.
+func (id USBID) String() string {
+	return fmt.Sprintf("%04x:%04x", id.VID, id.PID)
+}
+
+// errNoUSBLookup is returned by portUSBID on platforms without USB ID lookup.
+var errNoUSBLookup = errors.New("USB ID lookup is not supported on this platform")
+
+// KnownUSBIDs are the USB IDs of the serial interfaces used by supported
+// hardware.
+var KnownUSBIDs = []USBID{
+	{0x0483, 0x5740}, // STMicroelectronics Virtual COM Port (NanoVNA, NanoVNA-H, TinySA, LiteVNA)
+	{0x04b4, 0x0008}, // NanoVNA V2 / S-A-A-2 USB CDC
+	{0x10c4, 0xea60}, // Silicon Labs CP210x bridge
+	{0x1a86, 0x7523}, // WCH CH340 bridge
+}
+
+// ListDevicesFiltered is like ListDevices but returns only ports whose USB
+// ID is in vidPids; a nil vidPids means KnownUSBIDs. Looking up the USB ID
+// of a port is supported on Linux, through sysfs, and on macOS, through
+// ioreg; on other platforms an error is returned and callers can fall back
+// to ListDevices.
+func ListDevicesFiltered(vidPids []USBID) ([]string, error) {
+	if vidPids == nil {
+		vidPids = KnownUSBIDs
+	}
+	ports, err := ListDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []string
+	for _, port := range ports {
+		id, err := portUSBID(filepath.Base(port))
+		if err != nil {
+			if err == errNoUSBLookup {
+				return nil, err
+			}
+			continue // Not a USB device, or it went away
+		}
+		for _, want := range vidPids {
+			if id == want {
+				matched = append(matched, port)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
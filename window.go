@@ -0,0 +1,61 @@
+package nanovna
+
+import "math"
+
+// Window selects a taper applied before FFT-based transforms. Wider
+// windows lower the sidelobes at the cost of resolution; Rectangular (the
+// zero value) gives the sharpest peaks and the highest sidelobes.
+type Window int
+
+const (
+	WindowRectangular Window = iota // No taper, −13 dB sidelobes
+	WindowHann                      // Raised cosine, −31 dB sidelobes
+	WindowHamming                   // Raised cosine on a pedestal, −43 dB sidelobes
+	WindowBlackman                  // Three-term cosine, −58 dB sidelobes
+)
+
+// String returns the string representation of the window.
+func (w Window) String() string {
+	switch w {
+	case WindowRectangular:
+		return "Rectangular"
+	case WindowHann:
+		return "Hann"
+	case WindowHamming:
+		return "Hamming"
+	case WindowBlackman:
+		return "Blackman"
+	default:
+		return "Unknown"
+	}
+}
+
+// at returns the window coefficient at relative position x in [0, 1], with
+// the peak of 1 at x = 0.5. Unknown windows behave as Rectangular.
+func (w Window) at(x float64) float64 {
+	c := math.Cos(2 * math.Pi * x)
+	switch w {
+	case WindowHann:
+		return 0.5 - 0.5*c
+	case WindowHamming:
+		return 0.54 - 0.46*c
+	case WindowBlackman:
+		return 0.42 - 0.5*c + 0.08*math.Cos(4*math.Pi*x)
+	default:
+		return 1
+	}
+}
+
+// ApplyWindow returns a copy of data multiplied by the symmetric window w
+// spanning all of its points. A single point is left unchanged.
+func ApplyWindow(data []complex128, w Window) []complex128 {
+	out := make([]complex128, len(data))
+	for i, v := range data {
+		x := 0.5
+		if len(data) > 1 {
+			x = float64(i) / float64(len(data)-1)
+		}
+		out[i] = v * complex(w.at(x), 0)
+	}
+	return out
+}
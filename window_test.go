@@ -0,0 +1,57 @@
+package nanovna
+
+import (
+	"math"
+	"testing"
+)
+
+func TestApplyWindow(t *testing.T) {
+	data := []complex128{1, 1, 1, 1, 1}
+	tests := []struct {
+		w    Window
+		want []float64
+	}{
+		{WindowRectangular, []float64{1, 1, 1, 1, 1}},
+		{WindowHann, []float64{0, 0.5, 1, 0.5, 0}},
+		{WindowHamming, []float64{0.08, 0.54, 1, 0.54, 0.08}},
+		{WindowBlackman, []float64{0, 0.34, 1, 0.34, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.w.String(), func(t *testing.T) {
+			got := ApplyWindow(data, tt.w)
+			for i, v := range got {
+				if math.Abs(real(v)-tt.want[i]) > 1e-9 || imag(v) != 0 {
+					t.Errorf("[%d] = %v, want %v", i, v, tt.want[i])
+				}
+			}
+		})
+	}
+	if data[0] != 1 {
+		t.Error("ApplyWindow modified its input")
+	}
+	if got := ApplyWindow([]complex128{2i}, WindowHann); got[0] != 2i {
+		t.Errorf("single point = %v, want 2i", got[0])
+	}
+}
+
+func TestSweepData_TDRWindowSidelobes(t *testing.T) {
+	d := SweepData{}
+	for i := 0; i < 64; i++ {
+		d.Frequencies = append(d.Frequencies, 1e6+float64(i)*1e6)
+		d.S11 = append(d.S11, 1)
+	}
+	far := func(w Window) float64 {
+		res, err := d.TDR(0.66, w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		peak := 0.0
+		for _, v := range res.Impulse[len(res.Impulse)/4 : len(res.Impulse)/2] {
+			peak = math.Max(peak, v)
+		}
+		return peak
+	}
+	if rect, black := far(WindowRectangular), far(WindowBlackman); black >= rect {
+		t.Errorf("Blackman sidelobes %g not below Rectangular %g", black, rect)
+	}
+}